@@ -254,7 +254,7 @@ var (
 	flagChainOption = &cli.StringFlag{
 		Name: "chain",
 		Usage: "Use to include the certificate chain in the output, and to specify where to place it in the file. " +
-			"Options include: ignore | root-first | root-last",
+			"Options include: ignore | root-first | root-last | root-only",
 		Value:       "root-last",
 		Destination: &flags.chainOption,
 	}
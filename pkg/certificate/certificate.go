@@ -199,6 +199,51 @@ type Request struct {
 	Location      *Location
 	ValidityHours int
 	IssuerHint    string
+	// ExtraHeaders are added to the HTTP requests made for this request only. Authentication headers can't be overridden.
+	ExtraHeaders map[string]string
+	// ParsedCSR can be used instead of SetCSR when the caller already holds a parsed CSR.
+	// The connector checks its signature and PEM-encodes it if no CSR was set.
+	ParsedCSR *x509.CertificateRequest
+	// CAs don't guarantee to keep the order of SANs. When RequireSANOrder is set CheckCertificate fails if the issued
+	// DNS names are reordered, otherwise the reordering is only reported by IssuedCertificateWarnings.
+	RequireSANOrder bool
+	// ExpectedThumbprint is the SHA-256 (or SHA-1) fingerprint pinned out of band.
+	// When set RetrieveCertificate fails if the issued certificate has another fingerprint.
+	ExpectedThumbprint string
+	// Warnings are non-fatal issues reported by the server or found in the issued certificate.
+	// They are filled by RequestCertificate and RetrieveCertificate.
+	Warnings []string
+	// ReturnPendingOnTimeout makes RetrieveCertificate return endpoint.ErrCertificatePending instead of
+	// endpoint.ErrRetrieveCertificateTimeout when the certificate is still pending after Timeout,
+	// so the caller can resume with PickupID later.
+	ReturnPendingOnTimeout bool
+	// DefaultedFields lists the fields filled or overridden from the zone configuration by GenerateRequest,
+	// e.g. "KeyLength" or "Subject.Organization", to explain where unexpected values come from.
+	DefaultedFields []string
+	// RequireChain makes RetrieveCertificate fail if the certificate is returned without its issuer chain.
+	RequireChain bool
+	// CompleteChain makes connectors supporting it complete a missing issuer chain by downloading the issuers
+	// from the CA Issuers URLs of the certificate. It's off by default, as these URLs point to third-party hosts.
+	CompleteChain bool
+	// Origin identifies the integration issuing the certificate to the server. It takes precedence over
+	// a CustomFieldOrigin custom field, endpoint.SDKName is used when both are empty.
+	Origin string
+	// ClientIP identifies the client host in the request information sent to Venafi Cloud. The local address
+	// used to reach the API is detected when it's empty.
+	ClientIP string
+	// Retrieved is filled by RetrieveCertificate with the identifiers of the certificate it returned,
+	// e.g. the certificate ID resolved from PickupID or Thumbprint.
+	Retrieved *RetrievedCertificate
+}
+
+// RetrievedCertificate identifies a certificate returned by RetrieveCertificate
+type RetrievedCertificate struct {
+	// CertID is the ID of the certificate on the server
+	CertID string
+	// PickupID is the ID of the request that issued the certificate, it's empty when unknown
+	PickupID string
+	// Thumbprint is the uppercase hexadecimal SHA-1 fingerprint of the certificate
+	Thumbprint string
 }
 
 type RevocationRequest struct {
@@ -243,6 +288,17 @@ type CertificateInfo struct {
 	Thumbprint string
 	ValidFrom  time.Time
 	ValidTo    time.Time
+	// KeyUsage and ExtKeyUsage are filled when the server reports them
+	KeyUsage    x509.KeyUsage
+	ExtKeyUsage []x509.ExtKeyUsage
+	// Owner and Contacts are filled when the server reports them, Owner is empty for certificates without an owner
+	Owner    string
+	Contacts []string
+}
+
+// HasRemainingValidity returns true if the certificate is still valid for at least d from now
+func (ci CertificateInfo) HasRemainingValidity(d time.Duration) bool {
+	return !ci.ValidTo.Before(time.Now().Add(d))
 }
 
 // SetCSR sets CSR from PEM or DER format
@@ -267,6 +323,18 @@ func (request *Request) SetCSR(csr []byte) error {
 	return fmt.Errorf("%w: can't determine CSR type for %s", verror.UserDataError, csr)
 }
 
+// SetParsedCSR checks the signature of a parsed CSR and sets it in PEM format
+func (request *Request) SetParsedCSR(csr *x509.CertificateRequest) error {
+	if csr == nil || len(csr.Raw) == 0 {
+		return fmt.Errorf("%w: parsed CSR is empty", verror.UserDataError)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return fmt.Errorf("%w: invalid CSR signature: %v", verror.UserDataError, err)
+	}
+	request.csr = pem.EncodeToMemory(GetCertificateRequestPEMBlock(csr.Raw))
+	return nil
+}
+
 // GetCSR returns CSR in PEM format
 func (request Request) GetCSR() []byte {
 	return request.csr
@@ -344,6 +412,9 @@ func (request *Request) CheckCertificate(certPEM string) error {
 	if err != nil {
 		return err
 	}
+	if request.RequireSANOrder && !sameSANOrder(request.requestedDNSNames(), cert.DNSNames) {
+		return fmt.Errorf("%w: issued DNS names %v are not in requested order %v", verror.CertificateCheckError, cert.DNSNames, request.requestedDNSNames())
+	}
 	if request.PrivateKey != nil {
 		if request.KeyType.X509Type() != cert.PublicKeyAlgorithm {
 			return fmt.Errorf("%w: unmatched key type: %s, %s", verror.CertificateCheckError, request.KeyType.X509Type(), cert.PublicKeyAlgorithm)
@@ -401,6 +472,89 @@ func (request *Request) CheckCertificate(certPEM string) error {
 	return nil
 }
 
+// IssuedCertificateWarnings compares the certificate returned by server with the requested validity and subject.
+// Unlike CheckCertificate it doesn't fail on differences because a CA is allowed to adjust them (for example to trim validity),
+// the differences are returned as warnings instead. Tolerance is the allowed difference between requested and issued validity.
+func (request *Request) IssuedCertificateWarnings(certPEM string, tolerance time.Duration) ([]string, error) {
+	pemBlock, _ := pem.Decode([]byte(certPEM))
+	if pemBlock == nil {
+		return nil, fmt.Errorf("%w: invalid pem format certificate %s", verror.CertificateCheckError, certPEM)
+	}
+	cert, err := x509.ParseCertificate(pemBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	var warnings []string
+	if request.ValidityHours > 0 {
+		requested := time.Duration(request.ValidityHours) * time.Hour
+		issued := cert.NotAfter.Sub(cert.NotBefore)
+		if issued+tolerance < requested {
+			warnings = append(warnings, fmt.Sprintf("certificate validity was shortened by the CA: requested %v, issued %v (expires %s)",
+				requested, issued.Round(time.Minute), cert.NotAfter.Format(time.RFC3339)))
+		}
+	}
+	cn := request.Subject.CommonName
+	if cn == "" {
+		if csr := request.parseCSR(); csr != nil {
+			cn = csr.Subject.CommonName
+		}
+	}
+	if cn != "" && !strings.EqualFold(cn, cert.Subject.CommonName) {
+		warnings = append(warnings, fmt.Sprintf("certificate common name %q doesn't match requested %q", cert.Subject.CommonName, cn))
+	}
+	if requested := request.requestedDNSNames(); !sameSANOrder(requested, cert.DNSNames) {
+		warnings = append(warnings, fmt.Sprintf("certificate DNS names %v were reordered by the CA, requested %v", cert.DNSNames, requested))
+	}
+	return warnings, nil
+}
+
+// parseCSR returns the parsed request CSR or nil if there is no valid CSR
+func (request *Request) parseCSR() *x509.CertificateRequest {
+	if len(request.csr) == 0 {
+		return nil
+	}
+	b, _ := pem.Decode(request.csr)
+	if b == nil {
+		return nil
+	}
+	csr, err := x509.ParseCertificateRequest(b.Bytes)
+	if err != nil {
+		return nil
+	}
+	return csr
+}
+
+func (request *Request) requestedDNSNames() []string {
+	if len(request.DNSNames) != 0 {
+		return request.DNSNames
+	}
+	if csr := request.parseCSR(); csr != nil {
+		return csr.DNSNames
+	}
+	return nil
+}
+
+// sameSANOrder reports whether requested names appear in issued in the same relative order.
+// Names added or dropped by the CA are not taken into account.
+func sameSANOrder(requested, issued []string) bool {
+	position := make(map[string]int, len(issued))
+	for i, name := range issued {
+		position[strings.ToLower(name)] = i
+	}
+	last := -1
+	for _, name := range requested {
+		i, ok := position[strings.ToLower(name)]
+		if !ok {
+			continue
+		}
+		if i < last {
+			return false
+		}
+		last = i
+	}
+	return true
+}
+
 func publicKey(priv crypto.Signer) crypto.PublicKey {
 	if priv != nil {
 		return priv.Public()
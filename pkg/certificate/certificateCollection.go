@@ -17,6 +17,7 @@
 package certificate
 
 import (
+	"bytes"
 	"crypto"
 	"crypto/tls"
 	"crypto/x509"
@@ -36,6 +37,8 @@ const (
 	ChainOptionRootFirst
 	//ChainOptionIgnore specifies the chain should be ignored
 	ChainOptionIgnore
+	//ChainOptionRootOnly specifies the chain should contain the root certificate only, without the intermediates
+	ChainOptionRootOnly
 )
 
 //ChainOptionFromString converts the string to the corresponding ChainOption
@@ -45,11 +48,23 @@ func ChainOptionFromString(order string) ChainOption {
 		return ChainOptionRootFirst
 	case "ignore":
 		return ChainOptionIgnore
+	case "root-only":
+		return ChainOptionRootOnly
 	default:
 		return ChainOptionRootLast
 	}
 }
 
+//PrivateKeyFormat represents the encoding of the private key in a PEMCollection
+type PrivateKeyFormat int
+
+const (
+	//PrivateKeyFormatPKCS1 specifies PKCS#1 encoding for RSA keys and SEC 1 encoding for ECDSA keys
+	PrivateKeyFormatPKCS1 PrivateKeyFormat = iota
+	//PrivateKeyFormatPKCS8 specifies PKCS#8 encoding for both RSA and ECDSA keys
+	PrivateKeyFormatPKCS8
+)
+
 //PEMCollection represents a collection of PEM data
 type PEMCollection struct {
 	Certificate string   `json:",omitempty"`
@@ -106,7 +121,7 @@ func PEMCollectionFromBytes(certBytes []byte, chainOrder ChainOption) (*PEMColle
 				return nil, err
 			}
 			chain = append(chain, cert)
-		case "RSA PRIVATE KEY", "EC PRIVATE KEY":
+		case "RSA PRIVATE KEY", "EC PRIVATE KEY", "PRIVATE KEY":
 			privPEM = string(current)
 		}
 		current = remaining
@@ -124,6 +139,11 @@ func PEMCollectionFromBytes(certBytes []byte, chainOrder ChainOption) (*PEMColle
 					}
 				}
 			}
+		case ChainOptionRootOnly:
+			collection, err = NewPEMCollection(chain[0], nil, nil)
+			if root := chain[len(chain)-1]; len(chain) > 1 && bytes.Equal(root.RawIssuer, root.RawSubject) {
+				err = collection.AddChainElement(root)
+			}
 		default:
 			collection, err = NewPEMCollection(chain[0], nil, nil)
 			if len(chain) > 1 && chainOrder != ChainOptionIgnore {
@@ -194,3 +214,93 @@ func (col *PEMCollection) ToTLSCertificate() tls.Certificate {
 	}
 	return cert
 }
+
+//CertOnly returns the PEM of the leaf certificate or nil if there is no certificate
+func (col *PEMCollection) CertOnly() []byte {
+	return joinPEM(col.Certificate)
+}
+
+//KeyOnly returns the PEM of the private key or nil if there is no private key
+func (col *PEMCollection) KeyOnly() []byte {
+	return joinPEM(col.PrivateKey)
+}
+
+//ChainOnly returns the PEM blocks of the chain in the collection order or nil if there is no chain
+func (col *PEMCollection) ChainOnly() []byte {
+	return joinPEM(col.Chain...)
+}
+
+//joinPEM concatenates PEM blocks, each block ends with a single new line
+func joinPEM(blocks ...string) []byte {
+	var b []byte
+	for _, block := range blocks {
+		block = strings.TrimSpace(block)
+		if block == "" {
+			continue
+		}
+		b = append(b, block...)
+		b = append(b, '\n')
+	}
+	return b
+}
+
+//NormalizePrivateKey re-encodes the private key of the collection in the specified format regardless of its source encoding.
+//Encrypted private keys are not supported.
+func (col *PEMCollection) NormalizePrivateKey(format PrivateKeyFormat) error {
+	if col.PrivateKey == "" {
+		return nil
+	}
+	b, _ := pem.Decode([]byte(col.PrivateKey))
+	if b == nil {
+		return fmt.Errorf("%w: invalid private key PEM", verror.UserDataError)
+	}
+	//nolint:staticcheck
+	if x509.IsEncryptedPEMBlock(b) {
+		return fmt.Errorf("%w: encrypted private key can't be normalized", verror.UserDataError)
+	}
+	var key crypto.Signer
+	switch b.Type {
+	case "RSA PRIVATE KEY":
+		k, err := x509.ParsePKCS1PrivateKey(b.Bytes)
+		if err != nil {
+			return err
+		}
+		key = k
+	case "EC PRIVATE KEY":
+		k, err := x509.ParseECPrivateKey(b.Bytes)
+		if err != nil {
+			return err
+		}
+		key = k
+	case "PRIVATE KEY":
+		k, err := x509.ParsePKCS8PrivateKey(b.Bytes)
+		if err != nil {
+			return err
+		}
+		signer, ok := k.(crypto.Signer)
+		if !ok {
+			return fmt.Errorf("%w: unsupported private key type %T", verror.UserDataError, k)
+		}
+		key = signer
+	default:
+		return fmt.Errorf("%w: unsupported private key PEM type %s", verror.UserDataError, b.Type)
+	}
+
+	var p *pem.Block
+	var err error
+	switch format {
+	case PrivateKeyFormatPKCS1:
+		p, err = GetPrivateKeyPEMBock(key)
+	case PrivateKeyFormatPKCS8:
+		var der []byte
+		der, err = x509.MarshalPKCS8PrivateKey(key)
+		p = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	default:
+		return fmt.Errorf("%w: unknown private key format %d", verror.VcertError, format)
+	}
+	if err != nil {
+		return err
+	}
+	col.PrivateKey = string(pem.EncodeToMemory(p))
+	return nil
+}
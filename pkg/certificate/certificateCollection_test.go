@@ -17,8 +17,10 @@
 package certificate
 
 import (
+	"crypto"
 	"crypto/x509"
 	"encoding/pem"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -369,3 +371,94 @@ func TestChainOptionFromString(t *testing.T) {
 		t.Fatalf("ChainOptionFromString did not return the expected value of %v -- Actual value %v", ChainOptionRootLast, co)
 	}
 }
+
+func TestNormalizePrivateKey(t *testing.T) {
+	rsaKey, err := GenerateRSAPrivateKey(2048)
+	if err != nil {
+		t.Fatalf("Error generating RSA Private Key\nError: %s", err)
+	}
+	ecKey, err := GenerateECDSAPrivateKey(EllipticCurveP256)
+	if err != nil {
+		t.Fatalf("Error generating ECDSA Private Key\nError: %s", err)
+	}
+	for _, key := range []crypto.Signer{rsaKey, ecKey} {
+		col, err := NewPEMCollection(nil, key, nil)
+		if err != nil {
+			t.Fatalf("Error creating PEM collection\nError: %s", err)
+		}
+		pkcs1 := col.PrivateKey
+
+		err = col.NormalizePrivateKey(PrivateKeyFormatPKCS8)
+		if err != nil {
+			t.Fatalf("Error normalizing private key to PKCS#8\nError: %s", err)
+		}
+		b, _ := pem.Decode([]byte(col.PrivateKey))
+		if b == nil || b.Type != "PRIVATE KEY" {
+			t.Fatalf("Private key was not converted to PKCS#8: %s", col.PrivateKey)
+		}
+		parsed, err := x509.ParsePKCS8PrivateKey(b.Bytes)
+		if err != nil {
+			t.Fatalf("Error parsing PKCS#8 private key\nError: %s", err)
+		}
+		if !reflect.DeepEqual(parsed.(crypto.Signer).Public(), key.Public()) {
+			t.Fatalf("PKCS#8 private key doesn't match the original key")
+		}
+
+		err = col.NormalizePrivateKey(PrivateKeyFormatPKCS1)
+		if err != nil {
+			t.Fatalf("Error normalizing private key to PKCS#1\nError: %s", err)
+		}
+		if col.PrivateKey != pkcs1 {
+			t.Fatalf("Private key was not converted back to PKCS#1\nget:    %s\nexpect: %s", col.PrivateKey, pkcs1)
+		}
+	}
+
+	col := PEMCollection{PrivateKey: pkPEM}
+	if err := col.NormalizePrivateKey(PrivateKeyFormatPKCS8); err == nil {
+		t.Fatalf("Expected error for an encrypted private key")
+	}
+}
+
+func TestPEMCollectionSelectors(t *testing.T) {
+	var bytes []byte
+	for _, p := range []string{certPEM, rootPEM[0], rootPEM[1], pkPEM} {
+		bytes = append(bytes, []byte(p)...)
+		bytes = append(bytes, '\n')
+	}
+	pcc, err := PEMCollectionFromBytes(bytes, ChainOptionRootLast)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+
+	blockTypes := func(b []byte) []string {
+		var types []string
+		for {
+			var p *pem.Block
+			p, b = pem.Decode(b)
+			if p == nil {
+				return types
+			}
+			types = append(types, p.Type)
+		}
+	}
+	if types := blockTypes(pcc.CertOnly()); !reflect.DeepEqual(types, []string{"CERTIFICATE"}) {
+		t.Fatalf("CertOnly returned unexpected blocks %v", types)
+	}
+	if !strings.Contains(string(pcc.CertOnly()), strings.TrimSpace(pcc.Certificate)) {
+		t.Fatalf("CertOnly doesn't return the leaf certificate")
+	}
+	if types := blockTypes(pcc.KeyOnly()); len(types) != 1 || !strings.HasSuffix(types[0], "PRIVATE KEY") {
+		t.Fatalf("KeyOnly returned unexpected blocks %v", types)
+	}
+	if types := blockTypes(pcc.ChainOnly()); !reflect.DeepEqual(types, []string{"CERTIFICATE", "CERTIFICATE"}) {
+		t.Fatalf("ChainOnly returned unexpected blocks %v", types)
+	}
+	if string(pcc.ChainOnly()) != strings.TrimSpace(pcc.Chain[0])+"\n"+strings.TrimSpace(pcc.Chain[1])+"\n" {
+		t.Fatalf("ChainOnly doesn't keep the chain order")
+	}
+
+	empty := PEMCollection{}
+	if empty.CertOnly() != nil || empty.KeyOnly() != nil || empty.ChainOnly() != nil {
+		t.Fatalf("expected nil for an empty collection")
+	}
+}
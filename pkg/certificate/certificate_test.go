@@ -21,13 +21,17 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/pem"
+	"errors"
 	"math/big"
 	"net"
 	"os"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/verror"
 )
 
 func getCertificateRequestForTest() *Request {
@@ -560,6 +564,98 @@ func TestRequest_SetCSR_and_GetCSR(t *testing.T) {
 
 }
 
+func newCertificateForTest(t *testing.T, cn string, validity time.Duration, dnsNames ...string) string {
+	key, err := GenerateECDSAPrivateKey(EllipticCurveP256)
+	if err != nil {
+		t.Fatalf("Error generating ECDSA Private Key\nError: %s", err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(validity),
+		DNSNames:     dnsNames,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, key.Public(), key)
+	if err != nil {
+		t.Fatalf("Error creating certificate\nError: %s", err)
+	}
+	return string(pem.EncodeToMemory(GetCertificatePEMBlock(der)))
+}
+
+func TestRequest_IssuedCertificateWarnings(t *testing.T) {
+	req := Request{ValidityHours: 24 * 90}
+	req.Subject.CommonName = "validity.vfidev.com"
+
+	warnings, err := req.IssuedCertificateWarnings(newCertificateForTest(t, "validity.vfidev.com", 90*24*time.Hour), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 {
+		t.Fatalf("expected no warnings, got %v", warnings)
+	}
+
+	warnings, err = req.IssuedCertificateWarnings(newCertificateForTest(t, "validity.vfidev.com", 30*24*time.Hour), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "validity was shortened") {
+		t.Fatalf("expected validity warning, got %v", warnings)
+	}
+
+	warnings, err = req.IssuedCertificateWarnings(newCertificateForTest(t, "other.vfidev.com", 90*24*time.Hour-30*time.Minute), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "common name") {
+		t.Fatalf("expected only common name warning, got %v", warnings)
+	}
+}
+
+func TestRequest_SANOrder(t *testing.T) {
+	req := Request{DNSNames: []string{"a.vfidev.com", "b.vfidev.com", "c.vfidev.com"}}
+	req.Subject.CommonName = "a.vfidev.com"
+
+	ordered := newCertificateForTest(t, "a.vfidev.com", time.Hour, "a.vfidev.com", "extra.vfidev.com", "b.vfidev.com", "c.vfidev.com")
+	warnings, err := req.IssuedCertificateWarnings(ordered, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 {
+		t.Fatalf("expected no warnings, got %v", warnings)
+	}
+
+	reordered := newCertificateForTest(t, "a.vfidev.com", time.Hour, "c.vfidev.com", "a.vfidev.com", "b.vfidev.com")
+	warnings, err = req.IssuedCertificateWarnings(reordered, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "reordered") {
+		t.Fatalf("expected reordering warning, got %v", warnings)
+	}
+	if err = req.CheckCertificate(reordered); err != nil {
+		t.Fatalf("reordering should not fail the check by default, got %s", err)
+	}
+
+	req.RequireSANOrder = true
+	if err = req.CheckCertificate(reordered); !errors.Is(err, verror.CertificateCheckError) {
+		t.Fatalf("expected certificate check error, got %v", err)
+	}
+	if err = req.CheckCertificate(ordered); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCertificateInfo_HasRemainingValidity(t *testing.T) {
+	ci := CertificateInfo{ValidTo: time.Now().Add(30 * 24 * time.Hour)}
+	if !ci.HasRemainingValidity(30*24*time.Hour - time.Minute) {
+		t.Fatal("certificate expiring just after the threshold should have enough validity")
+	}
+	if ci.HasRemainingValidity(30*24*time.Hour + time.Minute) {
+		t.Fatal("certificate expiring just before the threshold should not have enough validity")
+	}
+}
+
 func pemRSADecode(priv string) *rsa.PrivateKey {
 	privPem, _ := pem.Decode([]byte(priv))
 
@@ -26,6 +26,7 @@ import (
 	"net"
 	"net/http"
 	"regexp"
+	"time"
 
 	"github.com/Venafi/vcert/v4/pkg/certificate"
 )
@@ -99,6 +100,23 @@ type Connector interface {
 type Filter struct {
 	Limit       *int
 	WithExpired bool
+	// CustomFields limits the result to certificates having all the given custom field values.
+	// Supported by Venafi Cloud only.
+	CustomFields []CustomFieldFilter
+	// IssuedAfter and IssuedBefore bound the validity start of the certificates, ExpiresAfter and ExpiresBefore
+	// their validity end. The bounds are inclusive and a zero time means unbounded. They are combined with
+	// WithExpired, so without it ExpiresAfter can only narrow the result to certificates which are still valid.
+	// Supported by Venafi Cloud only.
+	IssuedAfter   time.Time
+	IssuedBefore  time.Time
+	ExpiresAfter  time.Time
+	ExpiresBefore time.Time
+}
+
+// CustomFieldFilter matches certificates whose custom field Name has the given Value
+type CustomFieldFilter struct {
+	Name  string
+	Value string
 }
 
 // Authentication provides a struct for authentication data. Either specify User and Password for Trust Platform or specify an APIKey for Cloud.
@@ -392,46 +410,67 @@ func isComponentValid(ss []string, regexs []string, optional bool) bool {
 	return true
 }
 
-// UpdateCertificateRequest updates a certificate request based on the zone configuration retrieved from the remote endpoint
+// UpdateCertificateRequest updates a certificate request based on the zone configuration retrieved from the remote endpoint.
+// The names of the fields filled or overridden by the zone are stored in request.DefaultedFields.
 func (z *ZoneConfiguration) UpdateCertificateRequest(request *certificate.Request) {
+	request.DefaultedFields = nil
+	defaulted := func(field string) {
+		request.DefaultedFields = append(request.DefaultedFields, field)
+	}
+
 	if len(request.Subject.Organization) == 0 && z.Organization != "" {
 		request.Subject.Organization = []string{z.Organization}
+		defaulted("Subject.Organization")
 	}
 
 	if len(request.Subject.OrganizationalUnit) == 0 && z.OrganizationalUnit != nil {
 		request.Subject.OrganizationalUnit = z.OrganizationalUnit
+		defaulted("Subject.OrganizationalUnit")
 	}
 
 	if len(request.Subject.Country) == 0 && z.Country != "" {
 		request.Subject.Country = []string{z.Country}
+		defaulted("Subject.Country")
 	}
 
 	if len(request.Subject.Province) == 0 && z.Province != "" {
 		request.Subject.Province = []string{z.Province}
+		defaulted("Subject.Province")
 	}
 
 	if len(request.Subject.Locality) == 0 && z.Locality != "" {
 		request.Subject.Locality = []string{z.Locality}
+		defaulted("Subject.Locality")
 	}
 
+	signatureAlgorithm := request.SignatureAlgorithm
 	if z.HashAlgorithm != x509.UnknownSignatureAlgorithm {
 		request.SignatureAlgorithm = z.HashAlgorithm
 	} else {
 		request.SignatureAlgorithm = x509.SHA256WithRSA
 	}
+	if request.SignatureAlgorithm != signatureAlgorithm {
+		defaulted("SignatureAlgorithm")
+	}
 
 	if z.KeyConfiguration != nil {
+		if request.KeyType != z.KeyConfiguration.KeyType {
+			defaulted("KeyType")
+		}
 		request.KeyType = z.KeyConfiguration.KeyType
 		if len(z.KeyConfiguration.KeySizes) != 0 && request.KeyLength == 0 {
 			request.KeyLength = z.KeyConfiguration.KeySizes[0]
+			defaulted("KeyLength")
 		}
 		if len(z.KeyConfiguration.KeyCurves) != 0 && request.KeyCurve == certificate.EllipticCurveNotSet {
 			request.KeyCurve = z.KeyConfiguration.KeyCurves[0]
+			defaulted("KeyCurve")
 		}
 	} else {
 		// Zone config has no key length parameters, so we just pass user's -key-size or fall to default 2048
 		if request.KeyType == certificate.KeyTypeRSA && request.KeyLength == 0 {
 			request.KeyLength = 2048
+			defaulted("KeyLength")
 		}
 	}
 }
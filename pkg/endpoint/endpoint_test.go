@@ -19,6 +19,7 @@ package endpoint
 import (
 	"crypto/x509"
 	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"reflect"
 	"sort"
 	"strings"
 	"testing"
@@ -115,6 +116,22 @@ func TestUpdateRequestSubjectMostlyEmpty(t *testing.T) {
 	}
 }
 
+func TestUpdateRequestDefaultedFields(t *testing.T) {
+	req := certificate.Request{}
+	req.Subject.CommonName = "vcert.test.vfidev.com"
+	req.Subject.Organization = []string{"Venafi, Inc"}
+	req.Subject.Country = []string{"US"}
+	req.KeyLength = 2048
+
+	z := getBaseZoneConfiguration()
+	z.UpdateCertificateRequest(&req)
+
+	expected := []string{"Subject.OrganizationalUnit", "Subject.Province", "Subject.Locality", "SignatureAlgorithm"}
+	if !reflect.DeepEqual(req.DefaultedFields, expected) {
+		t.Fatalf("unexpected defaulted fields\nget:    %v\nexpect: %v", req.DefaultedFields, expected)
+	}
+}
+
 func TestGoodValiateRequest(t *testing.T) {
 	req := new(certificate.Request)
 	req.Subject.CommonName = "vcert.test.vfidev.com"
@@ -0,0 +1,218 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+const (
+	urlIssuingTemplates urlResource = apiVersion + "certificateissuingtemplates"
+	urlApplications     urlResource = basePath + "applications"
+)
+
+type applicationOwner struct {
+	OwnerId   string `json:"ownerId"`
+	OwnerType string `json:"ownerType"`
+}
+
+type createApplicationRequest struct {
+	Name            string             `json:"name"`
+	Owners          []applicationOwner `json:"ownerIdsAndTypes"`
+	CitAliasToIdMap map[string]string  `json:"certificateIssuingTemplateAliasIdMap"`
+}
+
+// EnsureApplication returns the details of the application, creating it with an issuing template
+// made from spec if it doesn't exist yet. The name of the template is its alias in the application.
+// The authenticated user becomes the owner of a new application.
+func (c *Connector) EnsureApplication(name string, spec TemplateSpec) (*ApplicationDetails, error) {
+	if err := c.requireAuthentication("create an application"); err != nil {
+		return nil, err
+	}
+	details, err := c.fetchAppDetailsByName(name)
+	if err == nil {
+		return details, nil
+	}
+	if !errors.Is(err, verror.ApplicationNotFoundError) {
+		return nil, err
+	}
+	template, err := spec.toTemplate()
+	if err != nil {
+		return nil, err
+	}
+	owner := c.userDetails()
+	if owner == nil || owner.User == nil || owner.User.ID == "" {
+		return nil, fmt.Errorf("%w: user details don't contain the user ID to own the application", verror.AuthError)
+	}
+
+	created, err := c.postTemplate(template)
+	if err != nil {
+		return nil, err
+	}
+
+	details, err = c.postApplication(createApplicationRequest{
+		Name:            name,
+		Owners:          []applicationOwner{{OwnerId: owner.User.ID, OwnerType: "USER"}},
+		CitAliasToIdMap: map[string]string{spec.Name: created.ID},
+	})
+	if err != nil {
+		// don't leave behind a template no application uses
+		if deleteErr := c.deleteTemplate(created.ID); deleteErr != nil {
+			return nil, fmt.Errorf("%w (issuing template %s was created and not deleted: %v)", err, created.ID, deleteErr)
+		}
+		return nil, err
+	}
+	return details, nil
+}
+
+// ApplicationOwner is a user or a team owning an application
+type ApplicationOwner struct {
+	ID string
+	// Type is "USER" or "TEAM", "USER" if empty
+	Type string
+}
+
+// ApplicationSpec describes the application CreateApplication creates
+type ApplicationSpec struct {
+	Name string
+	// Owners of the application, the authenticated user if empty
+	Owners []ApplicationOwner
+	// Templates maps the aliases of the issuing templates in the application to the IDs of existing templates
+	Templates map[string]string
+	// ReturnExisting makes CreateApplication return the details of an existing application with the same name
+	// instead of a verror.ServerConflictError
+	ReturnExisting bool
+}
+
+// CreateApplication creates the application of spec and returns its details, so certificates can be requested
+// in its zones right away
+func (c *Connector) CreateApplication(spec ApplicationSpec) (*ApplicationDetails, error) {
+	if err := c.requireAuthentication("create an application"); err != nil {
+		return nil, err
+	}
+	if spec.Name == "" {
+		return nil, fmt.Errorf("%w: application name can not be empty", verror.UserDataError)
+	}
+	app := createApplicationRequest{Name: spec.Name, CitAliasToIdMap: map[string]string{}}
+	for alias, id := range spec.Templates {
+		if alias == "" || id == "" {
+			return nil, fmt.Errorf("%w: issuing template alias and ID can not be empty, got %q: %q", verror.UserDataError, alias, id)
+		}
+		app.CitAliasToIdMap[alias] = id
+	}
+	for _, owner := range spec.Owners {
+		if owner.ID == "" {
+			return nil, fmt.Errorf("%w: application owner ID can not be empty", verror.UserDataError)
+		}
+		ownerType := owner.Type
+		if ownerType == "" {
+			ownerType = "USER"
+		}
+		app.Owners = append(app.Owners, applicationOwner{OwnerId: owner.ID, OwnerType: ownerType})
+	}
+	if len(app.Owners) == 0 {
+		user := c.userDetails()
+		if user == nil || user.User == nil || user.User.ID == "" {
+			return nil, fmt.Errorf("%w: user details don't contain the user ID to own the application", verror.AuthError)
+		}
+		app.Owners = []applicationOwner{{OwnerId: user.User.ID, OwnerType: "USER"}}
+	}
+
+	details, err := c.fetchAppDetailsByName(spec.Name)
+	switch {
+	case err == nil:
+		if spec.ReturnExisting {
+			return details, nil
+		}
+		return nil, fmt.Errorf("%w: application %s already exists", verror.ServerConflictError, spec.Name)
+	case !errors.Is(err, verror.ApplicationNotFoundError):
+		return nil, err
+	}
+	details, err = c.postApplication(app)
+	if errors.Is(err, verror.ServerConflictError) && spec.ReturnExisting {
+		// created concurrently by someone else
+		return c.fetchAppDetailsByName(spec.Name)
+	}
+	return details, err
+}
+
+// postApplication creates the application and caches its details
+func (c *Connector) postApplication(app createApplicationRequest) (*ApplicationDetails, error) {
+	statusCode, _, body, err := c.request("POST", c.getURL(urlApplications), app)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != http.StatusCreated && statusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to create application: %w", mapStatusToError(statusCode, body))
+	}
+	var apps struct {
+		Applications []ApplicationDetails `json:"applications"`
+	}
+	if err = unmarshalJSON(body, &apps); err != nil || len(apps.Applications) == 0 {
+		return nil, fmt.Errorf("%w: failed to parse created application: %s", verror.ServerBadDataResponce, bodySnippet(body))
+	}
+	details := &apps.Applications[0]
+	c.cache.putAppDetails(app.Name, details, c.now())
+	return details, nil
+}
+
+// TemplateAlias is an issuing template of an application, the zone of the template is "application\\alias"
+type TemplateAlias struct {
+	Alias string
+	ID    string
+}
+
+// ListApplications returns the applications visible to the authenticated user
+func (c *Connector) ListApplications() ([]ApplicationDetails, error) {
+	if err := c.requireAuthentication("list applications"); err != nil {
+		return nil, err
+	}
+	statusCode, _, body, err := c.request("GET", c.getURL(urlApplications), nil)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list applications: %w", mapStatusToError(statusCode, body))
+	}
+	var apps struct {
+		Applications []ApplicationDetails `json:"applications"`
+	}
+	if err = unmarshalJSON(body, &apps); err != nil {
+		return nil, fmt.Errorf("%w: failed to parse applications: %s", verror.ServerBadDataResponce, bodySnippet(body))
+	}
+	return apps.Applications, nil
+}
+
+// ListTemplates returns the issuing templates of the application sorted by alias.
+// verror.ApplicationNotFoundError is returned if there is no such application.
+func (c *Connector) ListTemplates(appName string) ([]TemplateAlias, error) {
+	details, err := c.getAppDetailsByName(appName)
+	if err != nil {
+		return nil, err
+	}
+	templates := make([]TemplateAlias, 0, len(details.CitAliasToIdMap))
+	for alias, id := range details.CitAliasToIdMap {
+		templates = append(templates, TemplateAlias{Alias: alias, ID: id})
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Alias < templates[j].Alias })
+	return templates, nil
+}
@@ -0,0 +1,234 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+func TestMockEnsureApplication(t *testing.T) {
+	var mu sync.Mutex
+	apps := make(map[string][]byte)
+	var templates []certificateTemplate
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/" + basePath + "applications/name/Onboarding":
+			app, ok := apps["Onboarding"]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"errors":[{"code":10051,"message":"Unable to find application"}]}`))
+				return
+			}
+			_, _ = w.Write(app)
+		case "/" + string(urlIssuingTemplates):
+			var template certificateTemplate
+			_ = json.NewDecoder(r.Body).Decode(&template)
+			template.ID = fmt.Sprintf("template-%d", len(templates))
+			templates = append(templates, template)
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(map[string][]certificateTemplate{"certificateIssuingTemplates": {template}})
+		case "/" + string(urlApplications):
+			var req createApplicationRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			if len(req.Owners) != 1 || req.Owners[0].OwnerId != "mock-user" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			app, _ := json.Marshal(ApplicationDetails{ApplicationId: "app-1", Name: req.Name, CitAliasToIdMap: req.CitAliasToIdMap})
+			apps[req.Name] = app
+			w.WriteHeader(http.StatusCreated)
+			_, _ = fmt.Fprintf(w, `{"applications":[%s]}`, app)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	spec := TemplateSpec{
+		Name:                 "Default",
+		CertificateAuthority: "BUILTIN",
+		ProductName:          "Default Product",
+		Domains:              []string{"vfidev.com"},
+		KeyTypes:             []endpoint.AllowedKeyConfiguration{{KeyType: certificate.KeyTypeRSA, KeySizes: []int{2048}}},
+	}
+	if _, err := conn.EnsureApplication("Onboarding", TemplateSpec{Name: "Default"}); !errors.Is(err, verror.UserDataError) {
+		t.Fatalf("expected user data error for an invalid template, got %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		details, err := conn.EnsureApplication("Onboarding", spec)
+		if err != nil {
+			t.Fatalf("err is not nil, err: %s", err)
+		}
+		if details.ApplicationId != "app-1" || details.CitAliasToIdMap["Default"] != "template-0" {
+			t.Fatalf("unexpected application details %+v", details)
+		}
+	}
+	if len(templates) != 1 || templates[0].Name != "Default" || templates[0].Product.ProductName != "Default Product" ||
+		len(templates[0].KeyTypes) != 1 || len(templates[0].SANRegexes) == 0 {
+		t.Fatalf("expected a single issuing template to be created, got %+v", templates)
+	}
+}
+
+func TestMockEnsureApplicationDeletesTemplate(t *testing.T) {
+	var deleted []string
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/"+basePath+"applications/name/Onboarding":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[{"code":10051,"message":"Unable to find application"}]}`))
+		case r.URL.Path == "/"+string(urlIssuingTemplates) && r.Method == "POST":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"certificateIssuingTemplates":[{"id":"template-1","name":"Default"}]}`))
+		case r.URL.Path == "/"+string(urlIssuingTemplates)+"/template-1" && r.Method == "DELETE":
+			deleted = append(deleted, "template-1")
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/"+string(urlApplications):
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"errors":[{"code":10001,"message":"invalid owner"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	spec := TemplateSpec{
+		Name:                 "Default",
+		CertificateAuthority: "BUILTIN",
+		ProductName:          "Default Product",
+		KeyTypes:             []endpoint.AllowedKeyConfiguration{{KeyType: certificate.KeyTypeRSA, KeySizes: []int{2048}}},
+	}
+	if _, err := conn.EnsureApplication("Onboarding", spec); err == nil {
+		t.Fatalf("expected error when the application can not be created")
+	}
+	if len(deleted) != 1 {
+		t.Fatalf("expected the created issuing template to be deleted, deleted %v", deleted)
+	}
+
+	unauthenticated, err := NewConnector(server.URL, mockZone, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = unauthenticated.EnsureApplication("Onboarding", spec); !errors.Is(err, verror.AuthError) {
+		t.Fatalf("expected auth error, got %v", err)
+	}
+}
+
+func TestMockCreateApplication(t *testing.T) {
+	var mu sync.Mutex
+	apps := make(map[string][]byte)
+	var owners []applicationOwner
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/" + basePath + "applications/name/Onboarding":
+			app, ok := apps["Onboarding"]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"errors":[{"code":10051,"message":"Unable to find application"}]}`))
+				return
+			}
+			_, _ = w.Write(app)
+		case "/" + string(urlApplications):
+			var req createApplicationRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			owners = req.Owners
+			app, _ := json.Marshal(ApplicationDetails{ApplicationId: "app-1", Name: req.Name, CitAliasToIdMap: req.CitAliasToIdMap})
+			apps[req.Name] = app
+			w.WriteHeader(http.StatusCreated)
+			_, _ = fmt.Fprintf(w, `{"applications":[%s]}`, app)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	spec := ApplicationSpec{
+		Name:      "Onboarding",
+		Owners:    []ApplicationOwner{{ID: "team-1", Type: "TEAM"}, {ID: "user-2"}},
+		Templates: map[string]string{"Default": "template-1"},
+	}
+	details, err := conn.CreateApplication(spec)
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if details.ApplicationId != "app-1" || details.CitAliasToIdMap["Default"] != "template-1" {
+		t.Fatalf("unexpected application details %+v", details)
+	}
+	if fmt.Sprint(owners) != "[{team-1 TEAM} {user-2 USER}]" {
+		t.Fatalf("unexpected owners %v", owners)
+	}
+
+	if _, err = conn.CreateApplication(spec); !errors.Is(err, verror.ServerConflictError) {
+		t.Fatalf("expected a conflict for an existing application, got %v", err)
+	}
+	spec.ReturnExisting = true
+	details, err = conn.CreateApplication(spec)
+	if err != nil || details.ApplicationId != "app-1" {
+		t.Fatalf("expected the existing application, got %+v, %v", details, err)
+	}
+
+	_, err = conn.CreateApplication(ApplicationSpec{Name: "Other", Templates: map[string]string{"Default": ""}})
+	if !errors.Is(err, verror.UserDataError) {
+		t.Fatalf("expected a user data error for an empty template ID, got %v", err)
+	}
+}
+
+func TestMockListApplicationsAndTemplates(t *testing.T) {
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + string(urlApplications):
+			_, _ = w.Write([]byte(`{"applications":[{"id":"a1","name":"App","certificateIssuingTemplateAliasIdMap":{"Template":"t1"}},{"id":"a2","name":"Other"}]}`))
+		case "/" + basePath + "applications/name/App":
+			_, _ = w.Write([]byte(`{"id":"a1","name":"App","certificateIssuingTemplateAliasIdMap":{"Template":"t1","Default":"t0"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	apps, err := conn.ListApplications()
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if len(apps) != 2 || apps[0].Name != "App" || apps[1].ApplicationId != "a2" {
+		t.Fatalf("unexpected applications %+v", apps)
+	}
+
+	templates, err := conn.ListTemplates("App")
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	expected := []TemplateAlias{{Alias: "Default", ID: "t0"}, {Alias: "Template", ID: "t1"}}
+	if fmt.Sprint(templates) != fmt.Sprint(expected) {
+		t.Fatalf("expected templates %v, got %v", expected, templates)
+	}
+	if _, err = conn.ListTemplates("Missing"); !errors.Is(err, verror.ApplicationNotFoundError) {
+		t.Fatalf("expected application not found error, got %v", err)
+	}
+}
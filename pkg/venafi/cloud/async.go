@@ -0,0 +1,106 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+// CertificateFuture is a certificate requested by RequestCertificateAsync that may not be issued yet
+type CertificateFuture struct {
+	done       chan struct{}
+	cancel     chan struct{}
+	cancelOnce sync.Once
+	pcc        *certificate.PEMCollection
+	err        error
+}
+
+// RequestCertificateAsync submits the request and polls for the issued certificate in background.
+// The polling stops when the certificate is issued, on error, when req.Timeout is reached (if set) or on Cancel.
+// The request must not be used by the caller until the future is done.
+func (c *Connector) RequestCertificateAsync(req *certificate.Request) *CertificateFuture {
+	f := &CertificateFuture{done: make(chan struct{}), cancel: make(chan struct{})}
+	// the HTTP client is created lazily, create it before copying so all the copies share it.
+	// The copy keeps lazily parsed connector state (like the zone) from being shared between goroutines.
+	c.getHTTPClient()
+	conn := c.snapshot()
+	go func() {
+		defer close(f.done)
+		f.pcc, f.err = conn.requestAndPoll(req, f)
+	}()
+	return f
+}
+
+func (c *Connector) requestAndPoll(req *certificate.Request, f *CertificateFuture) (*certificate.PEMCollection, error) {
+	pickupID, err := c.RequestCertificate(req)
+	if err != nil {
+		return nil, err
+	}
+	startTime := c.now()
+	timeout := req.Timeout
+	// RetrieveCertificate polls by itself when the timeout is set, the future polls instead to be cancelable
+	req.Timeout = 0
+	defer func() { req.Timeout = timeout }()
+	for {
+		pcc, err := c.RetrieveCertificate(req)
+		if !errors.As(err, &endpoint.ErrCertificatePending{}) {
+			return pcc, err
+		}
+		if timeout != 0 && c.now().After(startTime.Add(timeout)) {
+			if req.ReturnPendingOnTimeout {
+				return nil, err
+			}
+			return nil, endpoint.ErrRetrieveCertificateTimeout{CertificateID: pickupID}
+		}
+		select {
+		case <-f.cancel:
+			return nil, fmt.Errorf("%w: certificate request %s was canceled", verror.VcertError, pickupID)
+		case <-c.context().Done():
+			return nil, c.context().Err()
+		case <-time.After(c.getPollInterval()):
+		}
+	}
+}
+
+// Wait blocks until the certificate is issued, the request fails or the context is done.
+// The background polling continues if the context is done, use Cancel to stop it.
+func (f *CertificateFuture) Wait(ctx context.Context) (*certificate.PEMCollection, error) {
+	select {
+	case <-f.done:
+		return f.pcc, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Done returns a channel which is closed when the result is available
+func (f *CertificateFuture) Done() <-chan struct{} {
+	return f.done
+}
+
+// Cancel stops polling for the certificate. The request already submitted to the server is not affected.
+func (f *CertificateFuture) Cancel() {
+	f.cancelOnce.Do(func() { close(f.cancel) })
+}
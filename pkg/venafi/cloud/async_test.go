@@ -0,0 +1,145 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+)
+
+// newMockCA returns a handler issuing certificates for submitted CSRs. Each request is reported PENDING once.
+func newMockCA(t *testing.T) http.HandlerFunc {
+	caKey, err := certificate.GenerateECDSAPrivateKey(certificate.EllipticCurveP256)
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	caCert := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "Mock CA"}, IsCA: true, BasicConstraintsValid: true,
+		NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour)}
+	var mu sync.Mutex
+	issued := make(map[string][]byte)
+	polled := make(map[string]bool)
+	return func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		path := strings.TrimPrefix(r.URL.Path, "/")
+		switch {
+		case path == basePath+"applications/name/App":
+			_, _ = w.Write(successGetAppDetails)
+		case path == string(urlResourceCertificateRequests):
+			var cr certificateRequest
+			body, _ := ioutil.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &cr)
+			b, _ := pem.Decode([]byte(cr.CSR))
+			csr, err := x509.ParseCertificateRequest(b.Bytes)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			id := fmt.Sprintf("request-%d", len(issued))
+			der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{SerialNumber: big.NewInt(int64(len(issued) + 2)),
+				Subject: csr.Subject, NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour)}, caCert, csr.PublicKey, caKey)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			issued[id] = der
+			w.WriteHeader(http.StatusCreated)
+			_, _ = fmt.Fprintf(w, `{"certificateRequests":[{"id":"%s"}]}`, id)
+		case strings.HasPrefix(path, string(urlResourceCertificateRequests)+"/"):
+			id := strings.TrimPrefix(path, string(urlResourceCertificateRequests)+"/")
+			if !polled[id] {
+				polled[id] = true
+				_, _ = fmt.Fprintf(w, `{"id":"%s","status":"PENDING"}`, id)
+				return
+			}
+			_, _ = fmt.Fprintf(w, `{"id":"%s","status":"ISSUED","certificateIds":["cert-%s"]}`, id, id)
+		case strings.HasPrefix(path, string(urlResourceCertificates)+"/cert-"):
+			id := strings.TrimSuffix(strings.TrimPrefix(path, string(urlResourceCertificates)+"/cert-"), "/contents")
+			_, _ = w.Write(pem.EncodeToMemory(certificate.GetCertificatePEMBlock(issued[id])))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func TestMockRequestCertificateAsync(t *testing.T) {
+	defer func(interval time.Duration) { pollInterval = interval }(pollInterval)
+	pollInterval = 10 * time.Millisecond
+	conn, server := newMockConnector(t, newMockCA(t))
+	defer server.Close()
+
+	var reqs []*certificate.Request
+	var futures []*CertificateFuture
+	for i := 0; i < 3; i++ {
+		req := &certificate.Request{ParsedCSR: newTestCSR(t, fmt.Sprintf("async%d.vfidev.com", i))}
+		reqs = append(reqs, req)
+		futures = append(futures, conn.RequestCertificateAsync(req))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for i, f := range futures {
+		pcc, err := f.Wait(ctx)
+		if err != nil {
+			t.Fatalf("request %d: err is not nil, err: %s", i, err)
+		}
+		b, _ := pem.Decode([]byte(pcc.Certificate))
+		cert, err := x509.ParseCertificate(b.Bytes)
+		if err != nil {
+			t.Fatalf("request %d: err is not nil, err: %s", i, err)
+		}
+		if cert.Subject.CommonName != reqs[i].ParsedCSR.Subject.CommonName {
+			t.Fatalf("request %d: unexpected certificate %s", i, cert.Subject.CommonName)
+		}
+	}
+}
+
+func TestMockRequestAndRetrieveOnIssued(t *testing.T) {
+	defer func(interval time.Duration) { pollInterval = interval }(pollInterval)
+	pollInterval = 10 * time.Millisecond
+	conn, server := newMockConnector(t, newMockCA(t))
+	defer server.Close()
+
+	var deployed *certificate.PEMCollection
+	deployErr := errors.New("reload failed")
+	conn.SetOnIssued(func(pcc *certificate.PEMCollection) error {
+		deployed = pcc
+		return deployErr
+	})
+	req := &certificate.Request{ParsedCSR: newTestCSR(t, "deploy.vfidev.com"), Timeout: 10 * time.Second}
+	pcc, err := conn.RequestAndRetrieve(req)
+	if !errors.Is(err, deployErr) {
+		t.Fatalf("expected callback error, got: %v", err)
+	}
+	if deployed == nil || pcc != deployed || deployed.Certificate == "" {
+		t.Fatalf("expected callback to be invoked with the issued certificate")
+	}
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+)
+
+// requestCertificatesWorkers is the number of certificate requests RequestCertificates submits concurrently
+const requestCertificatesWorkers = 4
+
+// batchZoneCacheTTL is the TTL of the cache shared by the workers of RequestCertificates when the connector
+// doesn't cache
+const batchZoneCacheTTL = 5 * time.Minute
+
+// RequestCertificatesError is returned by RequestCertificates when some of the requests failed.
+// Errors has an entry for every request, nil for the ones that were submitted.
+type RequestCertificatesError struct {
+	Errors []error
+}
+
+func (e *RequestCertificatesError) Error() string {
+	var failed []string
+	for i, err := range e.Errors {
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("request %d: %v", i, err))
+		}
+	}
+	return fmt.Sprintf("%d of %d certificate requests failed: %s", len(failed), len(e.Errors), strings.Join(failed, "; "))
+}
+
+// Unwrap returns the error of the first failed request, so errors.Is and errors.As can be used on the result
+func (e *RequestCertificatesError) Unwrap() error {
+	for _, err := range e.Errors {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RequestCertificates submits the requests to the zone of the connector and returns their pickup IDs in order.
+// The application and issuing template are resolved once for all the requests, which are then submitted
+// concurrently. When some of the requests fail the IDs of the others are returned along with
+// a *RequestCertificatesError, the failed ones have an empty ID.
+func (c *Connector) RequestCertificates(reqs []*certificate.Request) ([]string, error) {
+	if err := c.requireAuthentication("request certificates"); err != nil {
+		return nil, err
+	}
+	// the copies made for the workers share the HTTP client and a cache, even if caching is disabled
+	c.getHTTPClient()
+	batch := c.snapshot()
+	if batch.cache == nil || batch.cache.ttl <= 0 {
+		batch.cache = newZoneCache(batchZoneCacheTTL)
+	}
+	if _, err := batch.zoneAppDetails(); err != nil {
+		return nil, err
+	}
+	// the template is only needed for the policy check, which is skipped when it can't be read
+	_, _ = batch.getTemplateByID()
+
+	ids := make([]string, len(reqs))
+	errs := make([]error, len(reqs))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < requestCertificatesWorkers && w < len(reqs); w++ {
+		wg.Add(1)
+		conn := batch
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				ids[i], errs[i] = conn.RequestCertificate(reqs[i])
+			}
+		}()
+	}
+	for i := range reqs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return ids, &RequestCertificatesError{Errors: errs}
+		}
+	}
+	return ids, nil
+}
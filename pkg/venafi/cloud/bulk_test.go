@@ -0,0 +1,84 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+func TestMockRequestCertificates(t *testing.T) {
+	var appLookups int32
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + basePath + "applications/name/App":
+			atomic.AddInt32(&appLookups, 1)
+			_, _ = w.Write(successGetAppDetails)
+		case "/" + string(urlResourceCertificateRequests):
+			var sent certificateRequest
+			_ = json.NewDecoder(r.Body).Decode(&sent)
+			b, _ := pem.Decode([]byte(sent.CSR))
+			csr, err := x509.ParseCertificateRequest(b.Bytes)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			// the pickup ID is the common name, so the order of the IDs can be checked
+			w.WriteHeader(http.StatusCreated)
+			_, _ = fmt.Fprintf(w, `{"certificateRequests":[{"id":%q}]}`, csr.Subject.CommonName)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+	conn.SetZoneCacheTTL(0)
+
+	var reqs []*certificate.Request
+	for i := 0; i < 10; i++ {
+		reqs = append(reqs, &certificate.Request{ParsedCSR: newTestCSR(t, fmt.Sprintf("bulk%d.vfidev.com", i))})
+	}
+	reqs[3].ClientIP = "invalid"
+
+	ids, err := conn.RequestCertificates(reqs)
+	var bulkErr *RequestCertificatesError
+	if !errors.As(err, &bulkErr) || !errors.Is(err, verror.UserDataError) {
+		t.Fatalf("expected the failure of a request to be reported, got %v", err)
+	}
+	for i, id := range ids {
+		if i == 3 {
+			if id != "" || bulkErr.Errors[i] == nil {
+				t.Fatalf("expected request 3 to fail, got ID %q", id)
+			}
+			continue
+		}
+		if expected := fmt.Sprintf("bulk%d.vfidev.com", i); id != expected || bulkErr.Errors[i] != nil {
+			t.Fatalf("expected ID %s for request %d, got %q, err: %v", expected, i, id, bulkErr.Errors[i])
+		}
+	}
+	if appLookups != 1 {
+		t.Fatalf("expected the application to be resolved once, got %d lookups", appLookups)
+	}
+}
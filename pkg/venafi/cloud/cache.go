@@ -0,0 +1,137 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+// zoneCache keeps application details and issuing templates so they are not fetched for every request.
+// A nil cache is valid and caches nothing.
+type zoneCache struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	apps      map[string]cachedAppDetails
+	templates map[string]cachedTemplate
+}
+
+type cachedAppDetails struct {
+	details *ApplicationDetails
+	expires time.Time
+}
+
+type cachedTemplate struct {
+	template *certificateTemplate
+	expires  time.Time
+}
+
+func newZoneCache(ttl time.Duration) *zoneCache {
+	return &zoneCache{
+		ttl:       ttl,
+		apps:      make(map[string]cachedAppDetails),
+		templates: make(map[string]cachedTemplate),
+	}
+}
+
+func (zc *zoneCache) getAppDetails(appName string, now time.Time) *ApplicationDetails {
+	if zc == nil {
+		return nil
+	}
+	zc.mu.Lock()
+	defer zc.mu.Unlock()
+	e, ok := zc.apps[appName]
+	if !ok || now.After(e.expires) {
+		return nil
+	}
+	return e.details
+}
+
+func (zc *zoneCache) putAppDetails(appName string, details *ApplicationDetails, now time.Time) {
+	if zc == nil || zc.ttl <= 0 {
+		return
+	}
+	zc.mu.Lock()
+	defer zc.mu.Unlock()
+	zc.apps[appName] = cachedAppDetails{details: details, expires: now.Add(zc.ttl)}
+}
+
+func (zc *zoneCache) getTemplate(zone string, now time.Time) *certificateTemplate {
+	if zc == nil {
+		return nil
+	}
+	zc.mu.Lock()
+	defer zc.mu.Unlock()
+	e, ok := zc.templates[zone]
+	if !ok || now.After(e.expires) {
+		return nil
+	}
+	return e.template
+}
+
+func (zc *zoneCache) putTemplate(zone string, template *certificateTemplate, now time.Time) {
+	if zc == nil || zc.ttl <= 0 {
+		return
+	}
+	zc.mu.Lock()
+	defer zc.mu.Unlock()
+	zc.templates[zone] = cachedTemplate{template: template, expires: now.Add(zc.ttl)}
+}
+
+func (zc *zoneCache) invalidate() {
+	if zc == nil {
+		return
+	}
+	zc.mu.Lock()
+	defer zc.mu.Unlock()
+	zc.apps = make(map[string]cachedAppDetails)
+	zc.templates = make(map[string]cachedTemplate)
+}
+
+// SetZoneCacheTTL sets how long application details and templates are cached by the connector.
+// Caching is disabled by default and by a zero or negative TTL.
+func (c *Connector) SetZoneCacheTTL(ttl time.Duration) {
+	c.cache = newZoneCache(ttl)
+}
+
+// RefreshZone fetches the application details and template of the zone again, bypassing the cache,
+// so policy changes made on the server are seen immediately.
+func (c *Connector) RefreshZone(zone string) error {
+	z := cloudZone{zone: zone}
+	if err := z.parseZone(); err != nil {
+		return fmt.Errorf("%w: %v", verror.UserDataError, err)
+	}
+	details, err := c.fetchAppDetailsByName(z.getApplicationName())
+	if err != nil {
+		return err
+	}
+	c.cache.putAppDetails(z.getApplicationName(), details, c.now())
+	template, err := c.fetchTemplate(z)
+	if err != nil {
+		return err
+	}
+	c.cache.putTemplate(z.String(), template, c.now())
+	return nil
+}
+
+// InvalidateCache drops all cached application details and templates
+func (c *Connector) InvalidateCache() {
+	c.cache.invalidate()
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMockRefreshZone(t *testing.T) {
+	fetches := make(map[string]int)
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		fetches[r.URL.Path]++
+		switch r.URL.Path {
+		case "/" + basePath + "applications/name/App":
+			_, _ = w.Write(successGetAppDetails)
+		case "/" + basePath + "applications/App/certificateissuingtemplates/Template":
+			_, _ = w.Write([]byte(`{"id":"t1t2t3t4-0000-11eb-0000-000000000000","name":"Template"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+	conn.SetZoneCacheTTL(time.Minute)
+	appPath := "/" + basePath + "applications/name/App"
+	templatePath := "/" + basePath + "applications/App/certificateissuingtemplates/Template"
+
+	for i := 0; i < 2; i++ {
+		if _, err := conn.getAppDetailsByName("App"); err != nil {
+			t.Fatalf("err is not nil, err: %s", err)
+		}
+		if _, err := conn.ReadZoneConfiguration(); err != nil {
+			t.Fatalf("err is not nil, err: %s", err)
+		}
+	}
+	if fetches[appPath] != 1 || fetches[templatePath] != 1 {
+		t.Fatalf("expected a single fetch within TTL, got %v", fetches)
+	}
+
+	if err := conn.RefreshZone(mockZone); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if fetches[appPath] != 2 || fetches[templatePath] != 2 {
+		t.Fatalf("expected RefreshZone to fetch again, got %v", fetches)
+	}
+	if _, err := conn.ReadZoneConfiguration(); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if fetches[templatePath] != 2 {
+		t.Fatalf("expected refreshed template to be cached, got %v", fetches)
+	}
+
+	conn.InvalidateCache()
+	if _, err := conn.getAppDetailsByName("App"); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if fetches[appPath] != 3 {
+		t.Fatalf("expected a fetch after InvalidateCache, got %v", fetches)
+	}
+
+	if err := conn.RefreshZone("invalid"); err == nil {
+		t.Fatal("expected error for invalid zone")
+	}
+}
+
+func TestMockZoneCache(t *testing.T) {
+	fetches := 0
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+basePath+"applications/name/App" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fetches++
+		_, _ = w.Write(successGetAppDetails)
+	})
+	defer server.Close()
+
+	fetch := func() {
+		if _, err := conn.getAppDetailsByName("App"); err != nil {
+			t.Fatalf("err is not nil, err: %s", err)
+		}
+	}
+	fetch()
+	fetch()
+	if fetches != 2 {
+		t.Fatalf("expected caching to be disabled by default, got %d fetches", fetches)
+	}
+
+	conn.SetZoneCacheTTL(time.Minute)
+	fetch()
+	conn.SetZone(mockZone)
+	fetch()
+	if fetches != 3 {
+		t.Fatalf("setting the same zone must keep the cache, got %d fetches", fetches)
+	}
+	// a copy scanning another zone doesn't drop the cache it shares
+	scan := conn.snapshot()
+	scan.SetZone("Third\\Template")
+	fetch()
+	if fetches != 3 {
+		t.Fatalf("changing the zone of a copy must keep the shared cache, got %d fetches", fetches)
+	}
+	conn.SetZone("Other\\Template")
+	fetch()
+	fetch()
+	if fetches != 4 {
+		t.Fatalf("changing the zone must invalidate the cache, got %d fetches", fetches)
+	}
+
+	conn.SetZoneCacheTTL(0)
+	fetch()
+	fetch()
+	if fetches != 6 {
+		t.Fatalf("expected a fetch for every call with caching disabled, got %d fetches", fetches)
+	}
+}
@@ -48,8 +48,12 @@ type certificateTemplate struct {
 	SubjectLRegexes        []string         `json:"subjectLRegexes,omitempty"`
 	SubjectCValues         []string         `json:"subjectCValues,omitempty"`
 	SANRegexes             []string         `json:"sanRegexes,omitempty"`
+	SANIPAddressRegexes    []string         `json:"sanIpAddressRegexes,omitempty"`
+	SANRFC822NameRegexes   []string         `json:"sanRfc822NameRegexes,omitempty"`
+	SANURIRegexes          []string         `json:"sanUniformResourceIdentifierRegexes,omitempty"`
 	KeyTypes               []allowedKeyType `json:"keyTypes,omitempty"`
 	KeyReuse               bool             `json:"keyReuse,omitempty"`
+	ValidityPeriod         string           `json:"validityPeriod,omitempty"`
 	RecommendedSettings    struct {
 		SubjectOValue, SubjectOUValue,
 		SubjectSTValue, SubjectLValue,
@@ -60,10 +64,13 @@ type certificateTemplate struct {
 		}
 		keyReuse bool
 	}
+	// KeyGeneratedByVenafiAllowed permits requests with service generated CSR
+	KeyGeneratedByVenafiAllowed bool `json:"keyGeneratedByVenafiAllowed,omitempty"`
 }
 type allowedKeyType struct {
-	KeyType    keyType
-	KeyLengths []int
+	KeyType    keyType  `json:"keyType"`
+	KeyLengths []int    `json:"keyLengths,omitempty"`
+	KeyCurves  []string `json:"keyCurves,omitempty"`
 }
 
 type keyType string
@@ -95,6 +102,16 @@ func (ct certificateTemplate) toPolicy() (p endpoint.Policy) {
 	p.SubjectLRegexes = addStartEndToArray(ct.SubjectLRegexes)
 	p.SubjectORegexes = addStartEndToArray(ct.SubjectORegexes)
 	p.DnsSanRegExs = addStartEndToArray(ct.SANRegexes)
+	// the other SAN types are left nil unless the template has their regexes
+	if len(ct.SANIPAddressRegexes) > 0 {
+		p.IpSanRegExs = addStartEndToArray(ct.SANIPAddressRegexes)
+	}
+	if len(ct.SANRFC822NameRegexes) > 0 {
+		p.EmailSanRegExs = addStartEndToArray(ct.SANRFC822NameRegexes)
+	}
+	if len(ct.SANURIRegexes) > 0 {
+		p.UriSanRegExs = addStartEndToArray(ct.SANURIRegexes)
+	}
 	p.AllowKeyReuse = ct.KeyReuse
 	allowWildCards := false
 	for _, s := range p.SubjectCNRegexes {
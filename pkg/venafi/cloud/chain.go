@@ -0,0 +1,219 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+// maxIssuerChainLength caps the number of issuers GetIssuerChain follows
+const maxIssuerChainLength = 5
+
+// GetIssuerChain builds the issuer chain of the PEM certificate by following the CA Issuers URLs of its
+// Authority Information Access extension. The chain is returned as PEM certificates, the closest issuer first.
+// The API key is not sent to the CA Issuers hosts.
+func (c *Connector) GetIssuerChain(certPEM string) ([]string, error) {
+	b, _ := pem.Decode([]byte(certPEM))
+	if b == nil {
+		return nil, fmt.Errorf("%w: invalid pem format certificate %s", verror.UserDataError, certPEM)
+	}
+	cert, err := x509.ParseCertificate(b.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to parse certificate: %v", verror.UserDataError, err)
+	}
+	var chain []string
+	for len(chain) < maxIssuerChainLength && len(cert.IssuingCertificateURL) > 0 && !bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+		issuer, err := c.fetchIssuer(cert.IssuingCertificateURL[0])
+		if err != nil {
+			return nil, err
+		}
+		if err = cert.CheckSignatureFrom(issuer); err != nil {
+			return nil, fmt.Errorf("%w: certificate %s is not signed by %s: %v", verror.ServerBadDataResponce, cert.Subject.CommonName, issuer.Subject.CommonName, err)
+		}
+		chain = append(chain, string(pem.EncodeToMemory(certificate.GetCertificatePEMBlock(issuer.Raw))))
+		cert = issuer
+	}
+	return chain, nil
+}
+
+// fetchIssuer downloads a DER or PEM encoded issuer certificate
+func (c *Connector) fetchIssuer(url string) (*x509.Certificate, error) {
+	res, err := c.getHTTPClient().Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to fetch issuer certificate from %s: %v", verror.ServerUnavailableError, url, err)
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to fetch issuer certificate from %s: %v", verror.ServerUnavailableError, url, err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: failed to fetch issuer certificate from %s: %s", verror.ServerError, url, res.Status)
+	}
+	if b, _ := pem.Decode(body); b != nil {
+		body = b.Bytes
+	}
+	issuer, err := x509.ParseCertificate(body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to parse issuer certificate from %s: %v", verror.ServerBadDataResponce, url, err)
+	}
+	return issuer, nil
+}
+
+// issuerChainCache keeps the issuer chains seen in responses or fetched with GetIssuerChain by issuer,
+// so the chain of a zone's CA is fetched once. A nil cache is valid and caches nothing.
+type issuerChainCache struct {
+	mu     sync.Mutex
+	chains map[string][]string
+}
+
+func newIssuerChainCache() *issuerChainCache {
+	return &issuerChainCache{chains: make(map[string][]string)}
+}
+
+func issuerKey(cert *x509.Certificate) string {
+	return string(cert.RawIssuer) + string(cert.AuthorityKeyId)
+}
+
+// get returns a root last chain which verifiably issued the certificate
+func (cc *issuerChainCache) get(cert *x509.Certificate) []string {
+	if cc == nil {
+		return nil
+	}
+	cc.mu.Lock()
+	chain := cc.chains[issuerKey(cert)]
+	cc.mu.Unlock()
+	if chain == nil || verifyChainLinkage(cert, chain) != nil {
+		return nil
+	}
+	return append([]string(nil), chain...)
+}
+
+func (cc *issuerChainCache) put(cert *x509.Certificate, chain []string) {
+	if cc == nil || len(chain) == 0 {
+		return
+	}
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.chains[issuerKey(cert)] = append([]string(nil), chain...)
+}
+
+// verifyChainLinkage checks that every certificate of the root last chain is signed by the next one
+func verifyChainLinkage(cert *x509.Certificate, chain []string) error {
+	for _, p := range chain {
+		b, _ := pem.Decode([]byte(p))
+		if b == nil {
+			return fmt.Errorf("%w: invalid pem format certificate in chain", verror.CertificateCheckError)
+		}
+		issuer, err := x509.ParseCertificate(b.Bytes)
+		if err != nil {
+			return fmt.Errorf("%w: failed to parse chain certificate: %v", verror.CertificateCheckError, err)
+		}
+		if err = cert.CheckSignatureFrom(issuer); err != nil {
+			return fmt.Errorf("%w: certificate %s is not signed by %s: %v", verror.CertificateCheckError, cert.Subject.CommonName, issuer.Subject.CommonName, err)
+		}
+		cert = issuer
+	}
+	return nil
+}
+
+func reverseChain(chain []string) []string {
+	reversed := make([]string, len(chain))
+	for i, p := range chain {
+		reversed[len(chain)-1-i] = p
+	}
+	return reversed
+}
+
+// completeChain fills the chain of a certificate returned without intermediates. With req.RequireChain
+// a missing chain is an error, otherwise the chain is taken from the issuer chains seen before or,
+// with req.CompleteChain, fetched with GetIssuerChain when possible. Complete chains of responses are remembered.
+func (c *Connector) completeChain(req *certificate.Request, pcc *certificate.PEMCollection, chainOption certificate.ChainOption) error {
+	if chainOption == certificate.ChainOptionIgnore {
+		return nil
+	}
+	b, _ := pem.Decode([]byte(pcc.Certificate))
+	if b == nil {
+		return nil
+	}
+	leaf, err := x509.ParseCertificate(b.Bytes)
+	if err != nil {
+		return nil
+	}
+	if len(pcc.Chain) > 0 {
+		chain := pcc.Chain
+		if chainOption == certificate.ChainOptionRootOnly {
+			// the intermediates were dropped, there is no complete chain to remember
+			return nil
+		}
+		if chainOption == certificate.ChainOptionRootFirst {
+			chain = reverseChain(chain)
+		}
+		if verifyChainLinkage(leaf, chain) == nil {
+			c.chains.put(leaf, chain)
+		}
+		return nil
+	}
+	if req.RequireChain {
+		return fmt.Errorf("%w: no issuer chain was returned for the certificate", verror.CertificateCheckError)
+	}
+	chain := c.chains.get(leaf)
+	if chain == nil && !req.CompleteChain {
+		return nil
+	}
+	if chain == nil {
+		chain, err = c.GetIssuerChain(pcc.Certificate)
+		if err != nil {
+			c.getLogger().Infof("Could not complete the issuer chain: %s", err)
+			return nil
+		}
+		c.chains.put(leaf, chain)
+	}
+	switch chainOption {
+	case certificate.ChainOptionRootFirst:
+		chain = reverseChain(chain)
+	case certificate.ChainOptionRootOnly:
+		chain = rootOf(chain)
+	}
+	pcc.Chain = chain
+	return nil
+}
+
+// rootOf returns the self-signed root of the root last chain, nil when the chain doesn't end with one
+func rootOf(chain []string) []string {
+	if len(chain) == 0 {
+		return nil
+	}
+	b, _ := pem.Decode([]byte(chain[len(chain)-1]))
+	if b == nil {
+		return nil
+	}
+	root, err := x509.ParseCertificate(b.Bytes)
+	if err != nil || !bytes.Equal(root.RawIssuer, root.RawSubject) {
+		return nil
+	}
+	return chain[len(chain)-1:]
+}
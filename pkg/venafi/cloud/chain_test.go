@@ -0,0 +1,231 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+func TestMockRetrieveCertificateLeafOnly(t *testing.T) {
+	caKey, err := certificate.GenerateECDSAPrivateKey(certificate.EllipticCurveP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "Mock CA"}, IsCA: true, BasicConstraintsValid: true,
+		NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour)}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var leafPEM []byte
+	var issuerFetches int
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ca.crt":
+			issuerFetches++
+			if r.Header.Get(headerNameAPIKey) != "" {
+				t.Errorf("API key must not be sent to the CA issuers host")
+			}
+			_, _ = w.Write(caDER)
+		case "/" + string(urlResourceCertificateRequests) + "/r1":
+			_, _ = w.Write([]byte(`{"id":"r1","status":"ISSUED","certificateIds":["c1"]}`))
+		case "/" + string(urlResourceCertificates) + "/c1/contents":
+			_, _ = w.Write(leafPEM)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	leafKey, err := certificate.GenerateECDSAPrivateKey(certificate.EllipticCurveP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{SerialNumber: big.NewInt(2), Subject: pkix.Name{CommonName: "leaf.vfidev.com"},
+		NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour), IssuingCertificateURL: []string{server.URL + "/ca.crt"}},
+		caTemplate, leafKey.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafPEM = pem.EncodeToMemory(certificate.GetCertificatePEMBlock(leafDER))
+
+	// the CA issuers host is only contacted when asked for
+	pcc, err := conn.RetrieveCertificate(&certificate.Request{PickupID: "r1"})
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if len(pcc.Chain) != 0 || issuerFetches != 0 {
+		t.Fatalf("expected the chain not to be completed by default, got %d certificates and %d fetches", len(pcc.Chain), issuerFetches)
+	}
+
+	pcc, err = conn.RetrieveCertificate(&certificate.Request{PickupID: "r1", CompleteChain: true})
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if len(pcc.Chain) != 1 || pcc.Chain[0] != string(pem.EncodeToMemory(certificate.GetCertificatePEMBlock(caDER))) {
+		t.Fatalf("expected the chain to be completed with the issuer, got %v", pcc.Chain)
+	}
+
+	_, err = conn.RetrieveCertificate(&certificate.Request{PickupID: "r1", RequireChain: true})
+	if !errors.Is(err, verror.CertificateCheckError) {
+		t.Fatalf("expected certificate check error for a missing chain, got %v", err)
+	}
+}
+
+func TestMockRetrieveCertificateCachedChain(t *testing.T) {
+	caKey, err := certificate.GenerateECDSAPrivateKey(certificate.EllipticCurveP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "Mock CA"}, IsCA: true, BasicConstraintsValid: true,
+		NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour)}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caPEM := pem.EncodeToMemory(certificate.GetCertificatePEMBlock(caDER))
+	issue := func(serial int64) []byte {
+		key, err := certificate.GenerateECDSAPrivateKey(certificate.EllipticCurveP256)
+		if err != nil {
+			t.Fatal(err)
+		}
+		der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{SerialNumber: big.NewInt(serial), Subject: pkix.Name{CommonName: "leaf.vfidev.com"},
+			NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour)}, caTemplate, key.Public(), caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pem.EncodeToMemory(certificate.GetCertificatePEMBlock(der))
+	}
+	full := append(issue(2), caPEM...)
+	leafOnly := issue(3)
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + string(urlResourceCertificateRequests) + "/full", "/" + string(urlResourceCertificateRequests) + "/leaf":
+			id := strings.TrimPrefix(r.URL.Path, "/"+string(urlResourceCertificateRequests)+"/")
+			_, _ = fmt.Fprintf(w, `{"id":"%s","status":"ISSUED","certificateIds":["%s"]}`, id, id)
+		case "/" + string(urlResourceCertificates) + "/full/contents":
+			_, _ = w.Write(full)
+		case "/" + string(urlResourceCertificates) + "/leaf/contents":
+			_, _ = w.Write(leafOnly)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	pcc, err := conn.RetrieveCertificate(&certificate.Request{PickupID: "leaf"})
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if len(pcc.Chain) != 0 {
+		t.Fatalf("chain can't be completed before the CA chain is known, got %v", pcc.Chain)
+	}
+	if _, err = conn.RetrieveCertificate(&certificate.Request{PickupID: "full"}); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	pcc, err = conn.RetrieveCertificate(&certificate.Request{PickupID: "leaf"})
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if len(pcc.Chain) != 1 || pcc.Chain[0] != string(caPEM) {
+		t.Fatalf("expected the chain to be completed with the cached CA chain, got %v", pcc.Chain)
+	}
+}
+
+func TestMockRetrieveCertificateChainOptions(t *testing.T) {
+	root, ica, leaf := newMockIssuedChain(t)
+	toPEM := func(cert *x509.Certificate) string {
+		return string(pem.EncodeToMemory(certificate.GetCertificatePEMBlock(cert.Raw)))
+	}
+
+	var chainOrders []string
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + string(urlResourceCertificateRequests) + "/r1":
+			_, _ = w.Write([]byte(`{"id":"r1","status":"ISSUED","certificateIds":["c1"]}`))
+		case "/" + string(urlResourceCertificates) + "/c1/contents":
+			chainOrders = append(chainOrders, r.URL.Query().Get("chainOrder"))
+			_, _ = w.Write([]byte(toPEM(leaf) + toPEM(ica) + toPEM(root)))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	cases := []struct {
+		option certificate.ChainOption
+		chain  []string
+	}{
+		{certificate.ChainOptionRootLast, []string{toPEM(ica), toPEM(root)}},
+		{certificate.ChainOptionIgnore, nil},
+		{certificate.ChainOptionRootOnly, []string{toPEM(root)}},
+	}
+	for _, c := range cases {
+		pcc, err := conn.RetrieveCertificate(&certificate.Request{PickupID: "r1", ChainOption: c.option})
+		if err != nil {
+			t.Fatalf("chain option %d: err is not nil, err: %s", c.option, err)
+		}
+		if pcc.Certificate != toPEM(leaf) || fmt.Sprint(pcc.Chain) != fmt.Sprint(c.chain) {
+			t.Fatalf("chain option %d: unexpected chain of %d certificates", c.option, len(pcc.Chain))
+		}
+	}
+	if fmt.Sprint(chainOrders) != "[EE_FIRST EE_FIRST EE_FIRST]" {
+		t.Fatalf("unexpected chain orders %v", chainOrders)
+	}
+}
+
+// newMockIssuedChain issues a root, an intermediate and a leaf certificate signed by the intermediate.
+func newMockIssuedChain(t *testing.T) (root, ica, leaf *x509.Certificate) {
+	issue := func(cn string, serial int64, isCA bool, parent *x509.Certificate, parentKey crypto.Signer) (*x509.Certificate, crypto.Signer) {
+		key, err := certificate.GenerateECDSAPrivateKey(certificate.EllipticCurveP256)
+		if err != nil {
+			t.Fatal(err)
+		}
+		template := &x509.Certificate{SerialNumber: big.NewInt(serial), Subject: pkix.Name{CommonName: cn}, IsCA: isCA, BasicConstraintsValid: true,
+			NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour)}
+		if parent == nil {
+			parent, parentKey = template, key
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert, key
+	}
+	root, rootKey := issue("Mock Root", 1, true, nil, nil)
+	ica, icaKey := issue("Mock ICA", 2, true, root, rootKey)
+	leaf, _ = issue("leaf.vfidev.com", 3, false, ica, icaKey)
+	return root, ica, leaf
+}
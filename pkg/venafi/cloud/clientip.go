@@ -0,0 +1,99 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	netUrl "net/url"
+	"sync"
+
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+// clientIdentifier returns the address identifying the client in the API client information of a request:
+// the IP set by the caller, otherwise the local address used to reach the API.
+func (c *Connector) clientIdentifier(clientIP string) (string, error) {
+	if clientIP == "" {
+		return c.outboundIP(), nil
+	}
+	if net.ParseIP(clientIP) == nil {
+		return "", fmt.Errorf("%w: invalid client IP %q", verror.UserDataError, clientIP)
+	}
+	return clientIP, nil
+}
+
+// outboundIPHolder keeps the outbound IP detected for a base URL. A nil holder is valid and keeps nothing.
+type outboundIPHolder struct {
+	mu      sync.Mutex
+	baseURL string
+	ip      string
+}
+
+// outboundIP returns the local address of the route to the API host, detected once per base URL.
+// Nothing is sent, dialing UDP only selects the route. endpoint.LocalIP is returned when the address
+// can't be detected or the requests go through a proxy, as the route to the proxy says nothing about the client.
+func (c *Connector) outboundIP() string {
+	baseURL := c.getBaseURL()
+	u, err := netUrl.Parse(baseURL)
+	if err != nil || u.Hostname() == "" || c.usesProxy(u) {
+		return endpoint.LocalIP
+	}
+	h := c.outboundIPs
+	if h == nil {
+		return detectOutboundIP(c.context(), u)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.ip == "" || h.baseURL != baseURL {
+		h.baseURL, h.ip = baseURL, detectOutboundIP(c.context(), u)
+	}
+	return h.ip
+}
+
+// usesProxy reports whether the requests to u go through the proxy set with SetProxy or the proxy of the environment
+func (c *Connector) usesProxy(u *netUrl.URL) bool {
+	c.state.rlock()
+	proxy := c.proxy
+	c.state.runlock()
+	if proxy != nil {
+		return true
+	}
+	envProxy, err := http.ProxyFromEnvironment(&http.Request{URL: u})
+	return err != nil || envProxy != nil
+}
+
+func detectOutboundIP(ctx context.Context, u *netUrl.URL) string {
+	port := u.Port()
+	if port == "" {
+		port = "443"
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return endpoint.LocalIP
+	}
+	defer conn.Close()
+	addr, ok := conn.LocalAddr().(*net.UDPAddr)
+	if !ok || addr.IP.IsUnspecified() {
+		return endpoint.LocalIP
+	}
+	return addr.IP.String()
+}
@@ -0,0 +1,48 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+)
+
+func TestMockOutboundIP(t *testing.T) {
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	defer server.Close()
+
+	if ip := conn.outboundIP(); ip != "127.0.0.1" {
+		t.Fatalf("expected the loopback address of the route to the mock server, got %s", ip)
+	}
+	// the detected address is kept, copies of the connector share it
+	conn.outboundIPs.ip = "192.0.2.1"
+	if ip := conn.WithContext(context.Background()).outboundIP(); ip != "192.0.2.1" {
+		t.Fatalf("expected the cached address, got %s", ip)
+	}
+
+	if err := conn.SetProxy("http://proxy.example.com:3128"); err != nil {
+		t.Fatal(err)
+	}
+	if ip := conn.outboundIP(); ip != endpoint.LocalIP {
+		t.Fatalf("expected %s when requests go through a proxy, got %s", endpoint.LocalIP, ip)
+	}
+}
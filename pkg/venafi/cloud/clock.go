@@ -0,0 +1,92 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const defaultClockSkewTolerance = 5 * time.Minute
+
+// SetClockSkewTolerance sets how much the local clock may differ from the server clock.
+// The tolerance is applied to validity comparisons like the expiry filter of ListCertificates,
+// so a local clock running ahead doesn't make valid certificates look expired.
+func (c *Connector) SetClockSkewTolerance(tolerance time.Duration) {
+	c.clockSkewTolerance = tolerance
+}
+
+// ClockSkew returns the difference between server and local time detected from the Date header of the last response.
+// It's zero until a response with the Date header is received.
+func (c *Connector) ClockSkew() time.Duration {
+	if c.clockSkew == nil {
+		return 0
+	}
+	return time.Duration(atomic.LoadInt64(c.clockSkew))
+}
+
+// ServerTime returns the time of the Date header of the last response, which is useful to timestamp
+// operations in audit logs. It's zero until a response with the Date header is received.
+func (c *Connector) ServerTime() time.Time {
+	if c.serverTime == nil {
+		return time.Time{}
+	}
+	if t := atomic.LoadInt64(c.serverTime); t != 0 {
+		return time.Unix(0, t).UTC()
+	}
+	return time.Time{}
+}
+
+func (c *Connector) now() time.Time {
+	if c.nowFunc != nil {
+		return c.nowFunc()
+	}
+	return time.Now()
+}
+
+// serverNow is the local time corrected by the detected clock skew
+func (c *Connector) serverNow() time.Time {
+	return c.now().Add(c.ClockSkew())
+}
+
+// validityThreshold is the earliest validity end of a certificate that may still be valid on the server clock
+func (c *Connector) validityThreshold() time.Time {
+	return c.now().Add(-c.clockSkewTolerance)
+}
+
+// detectClockSkew records the response Date header as the server time, compares the local clock with it
+// and warns if they differ more than tolerated
+func (c *Connector) detectClockSkew(res *http.Response) {
+	date, err := http.ParseTime(res.Header.Get("Date"))
+	if err != nil || c.clockSkew == nil {
+		return
+	}
+	if c.serverTime != nil {
+		atomic.StoreInt64(c.serverTime, date.UnixNano())
+	}
+	skew := date.Sub(c.now())
+	// Date header has a second resolution
+	if skew > -time.Second && skew < time.Second {
+		skew = 0
+	}
+	previous := time.Duration(atomic.SwapInt64(c.clockSkew, int64(skew)))
+	exceeds := func(d time.Duration) bool { return d > c.clockSkewTolerance || d < -c.clockSkewTolerance }
+	if exceeds(skew) && !exceeds(previous) {
+		c.getLogger().Infof("warning: local clock differs from Venafi Cloud server time by %v", skew)
+	}
+}
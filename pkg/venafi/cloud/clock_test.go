@@ -0,0 +1,128 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+)
+
+func TestMockClockSkewTolerance(t *testing.T) {
+	validityEnd := time.Now().Add(time.Hour)
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + basePath + "applications/name/App":
+			_, _ = w.Write(successGetAppDetails)
+		case "/" + string(urlResourceCertificateSearch):
+			var req SearchRequest
+			body, _ := ioutil.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &req)
+			for _, o := range req.Expression.Operands {
+				if o.Field == "validityEnd" {
+					threshold, _ := time.Parse(time.RFC3339, o.Value.(string))
+					if validityEnd.Before(threshold) {
+						_, _ = w.Write([]byte(`{"count":0,"certificates":[]}`))
+						return
+					}
+				}
+			}
+			_, _ = fmt.Fprintf(w, `{"count":1,"certificates":[{"id":"c1","validityEnd":"%s"}]}`, validityEnd.Format("2006-01-02T15:04:05-0700"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+	// local clock runs two hours ahead, so the certificate looks expired
+	conn.nowFunc = func() time.Time { return time.Now().Add(2 * time.Hour) }
+
+	conn.SetClockSkewTolerance(0)
+	infos, err := conn.ListCertificates(endpoint.Filter{})
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if len(infos) != 0 {
+		t.Fatalf("expected the certificate to be filtered out without tolerance, got %v", infos)
+	}
+	if skew := conn.ClockSkew(); skew > -time.Hour-59*time.Minute || skew < -2*time.Hour-time.Minute {
+		t.Fatalf("expected detected clock skew about -2h, got %v", skew)
+	}
+
+	conn.SetClockSkewTolerance(3 * time.Hour)
+	infos, err = conn.ListCertificates(endpoint.Filter{})
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if len(infos) != 1 || infos[0].ID != "c1" {
+		t.Fatalf("expected the tolerance to prevent false expiry, got %v", infos)
+	}
+}
+
+func TestMockServerTime(t *testing.T) {
+	serverTime := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", serverTime.Format(http.TimeFormat))
+		w.WriteHeader(http.StatusNotFound)
+	})
+	defer server.Close()
+
+	if !conn.ServerTime().IsZero() {
+		t.Fatalf("expected zero server time before any response, got %s", conn.ServerTime())
+	}
+	_, _, _, _ = conn.request("GET", conn.getURL(urlResourceUserAccounts), nil)
+	if !conn.ServerTime().Equal(serverTime) {
+		t.Fatalf("expected server time %s, got %s", serverTime, conn.ServerTime())
+	}
+	if now := conn.serverNow(); now.Before(serverTime) || now.After(serverTime.Add(time.Minute)) {
+		t.Fatalf("expected the corrected time to follow the server clock, got %s", now)
+	}
+}
+
+func TestMockClockSkewWarning(t *testing.T) {
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusNotFound)
+	})
+	defer server.Close()
+
+	// the standard logger is left alone when the connector isn't verbose
+	var std bytes.Buffer
+	log.SetOutput(&std)
+	defer log.SetOutput(os.Stderr)
+	_, _, _, _ = conn.request("GET", conn.getURL(urlResourceUserAccounts), nil)
+	if std.Len() != 0 {
+		t.Fatalf("expected nothing to be written to the standard logger, got %q", std.String())
+	}
+
+	// reset the detected skew, so the warning is written again
+	conn.clockSkew = new(int64)
+	logger := &recordingLogger{}
+	conn.SetLogger(logger)
+	_, _, _, _ = conn.request("GET", conn.getURL(urlResourceUserAccounts), nil)
+	if len(logger.messages["info"]) != 1 || !strings.Contains(logger.messages["info"][0], "local clock differs") {
+		t.Fatalf("expected the clock skew warning to be logged, got %v", logger.messages["info"])
+	}
+}
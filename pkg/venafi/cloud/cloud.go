@@ -18,15 +18,21 @@ package cloud
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha1"
+	"crypto/sha256"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"net"
 	"net/http"
+	"net/http/httptrace"
 	"strings"
 	"time"
 
@@ -57,6 +63,7 @@ type userDetails struct {
 
 type certificateRequestResponse struct {
 	CertificateRequests []certificateRequestResponseData `json:"certificateRequests,omitempty"`
+	Warnings            []responseError                  `json:"warnings,omitempty"`
 }
 
 type certificateRequestResponseData struct {
@@ -70,20 +77,22 @@ type certificateRequestResponseData struct {
 }
 
 type certificateRequestClientInfo struct {
-	Type       string `json:"type"`
-	Identifier string `json:"identifier"`
+	Type       string `json:"type,omitempty"`
+	Identifier string `json:"identifier,omitempty"`
 }
 
 type certificateRequest struct {
-	CSR                      string                       `json:"certificateSigningRequest,omitempty"`
-	ApplicationId            string                       `json:"applicationId,omitempty"`
-	TemplateId               string                       `json:"certificateIssuingTemplateId,omitempty"`
-	CertificateOwnerUserId   string                       `json:"certificateOwnerUserId,omitempty"`
-	ExistingCertificateId    string                       `json:"existingCertificateId,omitempty"`
-	ApiClientInformation     certificateRequestClientInfo `json:"apiClientInformation,omitempty"`
-	CertificateUsageMetadata []certificateUsageMetadata   `json:"certificateUsageMetadata,omitempty"`
-	ReuseCSR                 bool                         `json:"reuseCSR,omitempty"`
-	ValidityPeriod           string                       `json:"validityPeriod,omitempty"`
+	CSR                      string                        `json:"certificateSigningRequest,omitempty"`
+	ApplicationId            string                        `json:"applicationId,omitempty"`
+	TemplateId               string                        `json:"certificateIssuingTemplateId,omitempty"`
+	CertificateOwnerUserId   string                        `json:"certificateOwnerUserId,omitempty"`
+	ExistingCertificateId    string                        `json:"existingCertificateId,omitempty"`
+	ApiClientInformation     *certificateRequestClientInfo `json:"apiClientInformation,omitempty"`
+	CertificateUsageMetadata []certificateUsageMetadata    `json:"certificateUsageMetadata,omitempty"`
+	ReuseCSR                 bool                          `json:"reuseCSR,omitempty"`
+	ValidityPeriod           string                        `json:"validityPeriod,omitempty"`
+	IsVaaSGenerated          bool                          `json:"isVaaSGenerated,omitempty"`
+	CsrAttributes            *csrAttributes                `json:"csrAttributes,omitempty"`
 }
 
 type certificateStatus struct {
@@ -127,6 +136,8 @@ type importRequestCertInfo struct {
 	ApplicationIds           []string                   `json:"applicationIds"`
 	ApiClientInformation     apiClientInformation       `json:"apiClientInformation,omitempty"`
 	CertificateUsageMetadata []certificateUsageMetadata `json:"certificateUsageMetadata,omitempty"`
+	PrivateKey               string                     `json:"privateKey,omitempty"`
+	PrivateKeyPassphrase     string                     `json:"privateKeyPassphrase,omitempty"`
 }
 
 type importResponseCertInfo struct {
@@ -144,6 +155,12 @@ type importResponseCertInfo struct {
 	ApiClientInformation    apiClientInformation `json:"apiClientInformation,omitempty"`
 }
 
+// rawPayload is sent by request as is instead of being marshaled to JSON
+type rawPayload struct {
+	contentType string
+	data        []byte
+}
+
 type importResponse struct {
 	CertificateInformations []importResponseCertInfo `json:"certificateInformations"`
 }
@@ -151,6 +168,7 @@ type importResponse struct {
 type ApplicationDetails struct {
 	ApplicationId   string            `json:"id,omitempty"`
 	CitAliasToIdMap map[string]string `json:"certificateIssuingTemplateAliasIdMap,omitempty"`
+	Name            string            `json:"name,omitempty"`
 }
 
 //GenerateRequest generates a CertificateRequest based on the zone configuration, and returns the request along with the private key.
@@ -164,6 +182,9 @@ func (c *Connector) GenerateRequest(config *endpoint.ZoneConfiguration, req *cer
 			}
 		}
 		config.UpdateCertificateRequest(req)
+		if len(req.DefaultedFields) > 0 {
+			c.getLogger().Debugf("Fields set from the zone configuration: %s", strings.Join(req.DefaultedFields, ", "))
+		}
 		if err := req.GeneratePrivateKey(); err != nil {
 			return err
 		}
@@ -184,102 +205,374 @@ func (c *Connector) GenerateRequest(config *endpoint.ZoneConfiguration, req *cer
 }
 
 func (c *Connector) getURL(resource urlResource) string {
-	return fmt.Sprintf("%s%s", c.baseURL, resource)
+	return fmt.Sprintf("%s%s", c.getBaseURL(), resource)
 }
 
+// getHTTPClient returns the client set with SetHTTPClient, or the default client made on first use
 func (c *Connector) getHTTPClient() *http.Client {
+	c.state.rlock()
+	client := c.client
+	if client == nil {
+		client = c.defaultClient
+	}
+	c.state.runlock()
+	if client != nil {
+		return client
+	}
+	c.state.lock()
+	defer c.state.unlock()
 	if c.client != nil {
 		return c.client
 	}
+	if c.defaultClient == nil {
+		c.defaultClient = c.newDefaultHTTPClient()
+	}
+	return c.defaultClient
+}
+
+// newDefaultHTTPClient makes the client used when none was set with SetHTTPClient. It reuses connections
+// and trusts the connector trust pool, or the system roots if there is none. The client certificate set with
+// SetClientCertificate is presented to the servers requiring it, and the proxy set with SetProxy is used
+// instead of the proxy of the environment.
+func (c *Connector) newDefaultHTTPClient() *http.Client {
+	proxy := http.ProxyFromEnvironment
+	if c.proxy != nil {
+		proxy = http.ProxyURL(c.proxy)
+	}
 	var netTransport = &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
+		Proxy: proxy,
 		DialContext: (&net.Dialer{
 			Timeout:   30 * time.Second,
 			KeepAlive: 30 * time.Second,
 			DualStack: true,
 		}).DialContext,
 		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   10,
 		IdleConnTimeout:       90 * time.Second,
 		TLSHandshakeTimeout:   10 * time.Second,
 		ExpectContinueTimeout: 1 * time.Second,
 	}
 	tlsConfig := http.DefaultTransport.(*http.Transport).TLSClientConfig
 	/* #nosec */
-	if c.trust != nil {
+	if c.trust != nil || c.clientCert != nil {
 		if tlsConfig == nil {
 			tlsConfig = &tls.Config{}
 		} else {
 			tlsConfig = tlsConfig.Clone()
 		}
-		tlsConfig.RootCAs = c.trust
+		if c.trust != nil {
+			tlsConfig.RootCAs = c.trust
+		}
+		if c.clientCert != nil {
+			tlsConfig.Certificates = []tls.Certificate{*c.clientCert}
+		}
 	}
 	netTransport.TLSClientConfig = tlsConfig
-	c.client = &http.Client{
-		Timeout:   time.Second * 30,
+	timeout := c.httpTimeout
+	if timeout == 0 {
+		timeout = defaultHTTPTimeout
+	}
+	return &http.Client{
+		Timeout:   timeout,
 		Transport: netTransport,
 	}
-	return c.client
+}
+
+// applyTransportOptions makes the client of the connector from the client set with SetHTTPClient, so changing
+// or clearing the options never builds on the options applied before. The state lock must be held.
+func (c *Connector) applyTransportOptions() {
+	if c.callerClient == nil {
+		c.client = nil
+		return
+	}
+	c.client = c.withTransportOptions(c.callerClient)
+}
+
+// withTransportOptions returns a copy of the client presenting the client certificate and using the proxy
+// of the connector, if they are set. Custom round trippers are returned unchanged.
+func (c *Connector) withTransportOptions(client *http.Client) *http.Client {
+	if c.clientCert == nil && c.proxy == nil {
+		return client
+	}
+	rt := client.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	t, ok := rt.(*http.Transport)
+	if !ok {
+		return client
+	}
+	t = t.Clone()
+	if c.clientCert != nil {
+		if t.TLSClientConfig == nil {
+			t.TLSClientConfig = &tls.Config{}
+		}
+		t.TLSClientConfig.Certificates = []tls.Certificate{*c.clientCert}
+	}
+	if c.proxy != nil {
+		t.Proxy = http.ProxyURL(c.proxy)
+	}
+	copied := *client
+	copied.Transport = t
+	return &copied
+}
+
+// WithTrust returns a copy of the connector that uses the given trust pool for TLS connections to the server.
+// It can be used when one connector has to reach endpoints served behind TLS fronts signed by different CAs.
+func (c *Connector) WithTrust(trust *x509.CertPool) *Connector {
+	clone := c.snapshot()
+	clone.trust = trust
+	// the copy makes its own default client with the trust
+	clone.defaultClient = nil
+	if clone.callerClient != nil {
+		client := *clone.callerClient
+		client.Transport = transportWithTrust(client.Transport, trust)
+		clone.callerClient = &client
+		clone.applyTransportOptions()
+	}
+	return &clone
+}
+
+func transportWithTrust(rt http.RoundTripper, trust *x509.CertPool) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	t, ok := rt.(*http.Transport)
+	if !ok {
+		// custom round trippers are responsible for their own TLS configuration
+		return rt
+	}
+	t = t.Clone()
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+	t.TLSClientConfig.RootCAs = trust
+	return t
+}
+
+const headerNameAPIKey = "tppl-api-key"
+
+func isReservedHeader(name string) bool {
+	name = http.CanonicalHeaderKey(name)
+	return name == http.CanonicalHeaderKey(headerNameAPIKey) || name == "Authorization"
+}
+
+// SetDefaultHeaders sets headers added to every request made by the connector, such as a correlation ID or
+// a proxy authentication token. Headers of a request set with certificate.Request.ExtraHeaders take precedence.
+// The authentication headers can't be overridden.
+func (c *Connector) SetDefaultHeaders(headers map[string]string) error {
+	copied := make(map[string]string, len(headers))
+	for k, v := range headers {
+		if isReservedHeader(k) {
+			return fmt.Errorf("%w: header %s can't be overridden", verror.UserDataError, k)
+		}
+		copied[k] = v
+	}
+	c.headers = copied
+	return nil
+}
+
+// withExtraHeaders returns a copy of the connector that adds the given headers to every request
+func (c *Connector) withExtraHeaders(headers map[string]string) (*Connector, error) {
+	if len(headers) == 0 {
+		return c, nil
+	}
+	merged := make(map[string]string, len(c.headers)+len(headers))
+	for k, v := range c.headers {
+		merged[k] = v
+	}
+	for k, v := range headers {
+		if isReservedHeader(k) {
+			return nil, fmt.Errorf("%w: header %s can't be overridden", verror.UserDataError, k)
+		}
+		merged[k] = v
+	}
+	clone := c.snapshot()
+	clone.headers = merged
+	return &clone, nil
 }
 
 func (c *Connector) request(method string, url string, data interface{}, authNotRequired ...bool) (statusCode int, statusText string, body []byte, err error) {
-	if c.user == nil || c.user.Company == nil {
-		if !(len(authNotRequired) == 1 && authNotRequired[0]) {
-			err = fmt.Errorf("%w: must be autheticated to retieve certificate", verror.VcertError)
+	if !(len(authNotRequired) == 1 && authNotRequired[0]) {
+		if err = c.requireAuthentication("send requests"); err != nil {
 			return
 		}
 	}
 
-	var payload io.Reader
 	var b []byte
-	if method == "POST" {
-		b, _ = json.Marshal(data)
+	contentType := "application/json"
+	if hasBody(method) {
+		if raw, ok := data.(rawPayload); ok {
+			b, contentType = raw.data, raw.contentType
+		} else {
+			b, _ = json.Marshal(data)
+		}
+	}
+
+	urls := []string{url}
+	if baseURL := c.getBaseURL(); strings.HasPrefix(url, baseURL) {
+		for _, failoverURL := range c.failoverURLs {
+			urls = append(urls, failoverURL+strings.TrimPrefix(url, baseURL))
+		}
+	}
+	ctx := c.context()
+	retries := 0
+	if c.tracer != nil {
+		var span Span
+		ctx, span = c.tracer.Start(ctx, c.spanName(method, url))
+		defer func() {
+			span.SetAttribute("http.method", method)
+			span.SetAttribute("http.status_code", statusCode)
+			span.SetAttribute("retry.count", retries)
+			span.End()
+		}()
+	}
+	var header http.Header
+	var sent bool
+	for attempt := 0; ; attempt++ {
+		for i, u := range urls {
+			if attempt > 0 || i > 0 {
+				retries++
+			}
+			statusCode, statusText, header, body, sent, err = c.send(ctx, method, u, b, contentType)
+			if i == len(urls)-1 || !failover(method, statusCode, err, sent) {
+				break
+			}
+			c.getLogger().Infof("Request to %s failed, trying %s", u, urls[i+1])
+		}
+		delay, ok := c.retryDelay(method, attempt, statusCode, header, err, sent)
+		if !ok {
+			return
+		}
+		// retrying must not outlive the caller's deadline, the last error is returned instead
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			err = fmt.Errorf("not retrying %s %s past the deadline: %w", method, url, lastError(statusCode, body, err))
+			return
+		}
+		c.getLogger().Infof("Request to %s failed, retrying in %s", url, delay)
+		select {
+		case <-ctx.Done():
+			err = fmt.Errorf("%w: retrying %s %s after: %v", ctx.Err(), method, url, lastError(statusCode, body, err))
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// failover reports whether a failed request is sent again to the next URL. Like retries, requests which are
+// not idempotent are only sent again when the server didn't receive them.
+func failover(method string, statusCode int, err error, sent bool) bool {
+	if method == "GET" {
+		return errors.Is(err, verror.ServerUnavailableError) || statusCode >= http.StatusInternalServerError
+	}
+	return errors.Is(err, verror.ServerUnavailableError) && !sent
+}
+
+// lastError is the error of the last attempt of a request given up before it succeeded
+func lastError(statusCode int, body []byte, err error) error {
+	if err != nil {
+		return err
+	}
+	return mapStatusToError(statusCode, body)
+}
+
+// hasBody reports whether requests of the method carry a JSON payload
+func hasBody(method string) bool {
+	return method == "POST" || method == "PUT"
+}
+
+// send makes a single request. sent reports whether the request was written to the server, a request which
+// failed before can be retried even if it's not idempotent.
+func (c *Connector) send(ctx context.Context, method string, url string, b []byte, contentType string) (statusCode int, statusText string, header http.Header, body []byte, sent bool, err error) {
+	var payload io.Reader
+	if hasBody(method) {
 		payload = bytes.NewReader(b)
 	}
 
-	r, err := http.NewRequest(method, url, payload)
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		WroteRequest: func(httptrace.WroteRequestInfo) { sent = true },
+	})
+	r, err := http.NewRequestWithContext(ctx, method, url, payload)
 	if err != nil {
 		err = fmt.Errorf("%w: %v", verror.VcertError, err)
 		return
 	}
-	if c.apiKey != "" {
-		r.Header.Add("tppl-api-key", c.apiKey)
+	for k, v := range c.headers {
+		if !isReservedHeader(k) {
+			r.Header.Set(k, v)
+		}
+	}
+	// secret is the credential sent with the request, it's masked in the errors and the body returned
+	var secret string
+	if c.credentials != nil {
+		if secret, err = c.credentials.get(ctx, c.now()); err != nil {
+			return
+		}
+		r.Header.Set("Authorization", "Bearer "+secret)
+	} else if secret = c.getAPIKey(); secret != "" {
+		r.Header.Set(headerNameAPIKey, secret)
 	}
-	if method == "POST" {
+	if hasBody(method) {
 		r.Header.Add("Accept", "application/json")
-		r.Header.Add("content-type", "application/json")
+		r.Header.Add("content-type", contentType)
 	} else {
 		r.Header.Add("Accept", "*/*")
 	}
 	r.Header.Add("cache-control", "no-cache")
+	if c.tracer != nil {
+		c.tracer.Inject(ctx, r.Header)
+	}
+	if c.signer != nil {
+		if err = c.signer.Sign(r, b); err != nil {
+			err = fmt.Errorf("%w: failed to sign request: %v", verror.VcertError, err)
+			return
+		}
+	}
+
+	if c.logger != nil || c.verbose {
+		c.getLogger().Debugf("Sending %s %s, headers: %s", method, url, redactedHeaders(r.Header))
+	}
 
 	var httpClient = c.getHTTPClient()
 
 	res, err := httpClient.Do(r)
 	if err != nil {
-		err = fmt.Errorf("%w: %v", verror.ServerUnavailableError, err)
+		if ctx.Err() != nil {
+			// the caller gave up, it's not a server failure to retry
+			err = ctx.Err()
+			return
+		}
+		err = fmt.Errorf("%w: %s", verror.ServerUnavailableError, redactSecret(err.Error(), secret))
 		return
 	}
 	statusCode = res.StatusCode
 	statusText = res.Status
+	header = res.Header
+	c.detectClockSkew(res)
+	c.tlsState.record(res)
+	c.rateLimit.record(res.Header, time.Now())
 
 	defer res.Body.Close()
 	body, err = ioutil.ReadAll(res.Body)
 	if err != nil {
-		err = fmt.Errorf("%w: %v", verror.ServerError, err)
+		err = fmt.Errorf("%w: %s", verror.ServerError, redactSecret(err.Error(), secret))
+	}
+	if secret != "" && bytes.Contains(body, []byte(secret)) {
+		body = bytes.ReplaceAll(body, []byte(secret), []byte(redacted))
 	}
 	// Do not enable trace in production
 	trace := false // IMPORTANT: sensitive information can be diclosured
 	// I hope you know what are you doing
 	if trace {
 		log.Println("#################")
-		if method == "POST" {
+		if hasBody(method) {
 			log.Printf("JSON sent for %s\n%s\n", url, string(b))
 		} else {
 			log.Printf("%s request sent to %s\n", method, url)
 		}
 		log.Printf("Response:\n%s\n", string(body))
-	} else if c.verbose {
-		log.Printf("Got %s status for %s %s\n", statusText, method, url)
+	} else {
+		c.getLogger().Debugf("Got %s status for %s %s", statusText, method, url)
 	}
 	return
 }
@@ -288,20 +581,12 @@ func parseUserDetailsResult(expectedStatusCode int, httpStatusCode int, httpStat
 	if httpStatusCode == expectedStatusCode {
 		return parseUserDetailsData(body)
 	}
-	respErrors, err := parseResponseErrors(body)
-	if err != nil {
-		return nil, err // parseResponseErrors always return verror.ServerError
-	}
-	respError := fmt.Sprintf("unexpected status code on Venafi Cloud registration. Status: %s\n", httpStatus)
-	for _, e := range respErrors {
-		respError += fmt.Sprintf("Error Code: %d Error: %s\n", e.Code, e.Message)
-	}
-	return nil, fmt.Errorf("%w: %v", verror.ServerError, respError)
+	return nil, fmt.Errorf("failed to read user details: %w", mapStatusToError(httpStatusCode, body))
 }
 
 func parseUserDetailsData(b []byte) (*userDetails, error) {
 	var data userDetails
-	err := json.Unmarshal(b, &data)
+	err := unmarshalJSON(b, &data)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", verror.ServerError, err)
 	}
@@ -316,25 +601,16 @@ func parseZoneConfigurationResult(httpStatusCode int, httpStatus string, body []
 	case http.StatusBadRequest, http.StatusNotFound:
 		return nil, verror.ZoneNotFoundError
 	default:
-		respErrors, err := parseResponseErrors(body)
-		if err != nil {
-			return nil, err
-		}
-
-		respError := fmt.Sprintf("Unexpected status code on Venafi Cloud zone read. Status: %s\n", httpStatus)
-		for _, e := range respErrors {
-			if e.Code == 10051 {
-				return nil, verror.ZoneNotFoundError
-			}
-			respError += fmt.Sprintf("Error Code: %d Error: %s\n", e.Code, e.Message)
+		if hasErrorCode(body, 10051) {
+			return nil, verror.ZoneNotFoundError
 		}
-		return nil, fmt.Errorf("%w: %v", verror.ServerError, respError)
+		return nil, fmt.Errorf("failed to read zone: %w", mapStatusToError(httpStatusCode, body))
 	}
 }
 
 func parseZoneConfigurationData(b []byte) (*zone, error) {
 	var data zone
-	err := json.Unmarshal(b, &data)
+	err := unmarshalJSON(b, &data)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", verror.ServerError, err)
 	}
@@ -348,25 +624,16 @@ func parseCertificateTemplateResult(httpStatusCode int, httpStatus string, body
 	case http.StatusBadRequest:
 		return nil, verror.ZoneNotFoundError
 	default:
-		respErrors, err := parseResponseErrors(body)
-		if err != nil {
-			return nil, err
-		}
-
-		respError := fmt.Sprintf("Unexpected status code on Venafi Cloud zone read. Status: %s\n", httpStatus)
-		for _, e := range respErrors {
-			if e.Code == 10051 {
-				return nil, verror.ZoneNotFoundError
-			}
-			respError += fmt.Sprintf("Error Code: %d Error: %s\n", e.Code, e.Message)
+		if hasErrorCode(body, 10051) {
+			return nil, verror.ZoneNotFoundError
 		}
-		return nil, fmt.Errorf("%w: %v", verror.ServerError, respError)
+		return nil, fmt.Errorf("failed to read zone: %w", mapStatusToError(httpStatusCode, body))
 	}
 }
 
 func parseCertificateTemplateData(body []byte) (*certificateTemplate, error) {
 	var ct certificateTemplate
-	err := json.Unmarshal(body, &ct)
+	err := unmarshalJSON(body, &ct)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", verror.ServerError, err)
 	}
@@ -378,22 +645,13 @@ func parseCertificateRequestResult(httpStatusCode int, httpStatus string, body [
 	case http.StatusCreated:
 		return parseCertificateRequestData(body)
 	default:
-		respErrors, err := parseResponseErrors(body)
-		if err != nil {
-			return nil, err
-		}
-
-		respError := fmt.Sprintf("Unexpected status code on Venafi Cloud zone read. Status: %s\n", httpStatus)
-		for _, e := range respErrors {
-			respError += fmt.Sprintf("Error Code: %d Error: %s\n", e.Code, e.Message)
-		}
-		return nil, fmt.Errorf("%w: %v", verror.ServerError, respError)
+		return nil, fmt.Errorf("failed to request certificate: %w", mapStatusToError(httpStatusCode, body))
 	}
 }
 
 func parseCertificateRequestData(b []byte) (*certificateRequestResponse, error) {
 	var data certificateRequestResponse
-	err := json.Unmarshal(b, &data)
+	err := unmarshalJSON(b, &data)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", verror.ServerError, err)
 	}
@@ -410,32 +668,66 @@ func certThumbprint(asn1 []byte) string {
 	return strings.ToUpper(fmt.Sprintf("%x", h))
 }
 
+func certThumbprintSHA256(asn1 []byte) string {
+	h := sha256.Sum256(asn1)
+	return strings.ToUpper(fmt.Sprintf("%x", h))
+}
+
+// normalizeFingerprint drops the separators of a hex fingerprint and upper-cases it,
+// so fingerprints from the API and from users compare equal to certThumbprint
+func normalizeFingerprint(fp string) string {
+	return strings.ToUpper(strings.NewReplacer(":", "", ".", "", " ", "").Replace(fp))
+}
+
+// checkThumbprint compares the leaf certificate fingerprint with the expected one.
+// The expected fingerprint may be SHA-256 or SHA-1 (as used by Venafi Cloud), with or without colons.
+func checkThumbprint(certPEM string, expected string) error {
+	expected = normalizeFingerprint(expected)
+	b, _ := pem.Decode([]byte(certPEM))
+	if b == nil {
+		return fmt.Errorf("%w: invalid pem format certificate %s", verror.CertificateCheckError, certPEM)
+	}
+	var actual string
+	switch len(expected) {
+	case sha256.Size * 2:
+		actual = certThumbprintSHA256(b.Bytes)
+	case sha1.Size * 2:
+		actual = certThumbprint(b.Bytes)
+	default:
+		return fmt.Errorf("%w: expected thumbprint %s is neither SHA-256 nor SHA-1", verror.UserDataError, expected)
+	}
+	if actual != expected {
+		return fmt.Errorf("%w: certificate thumbprint %s doesn't match expected %s", verror.CertificateCheckError, actual, expected)
+	}
+	return nil
+}
+
+// setRetrieved records the identifiers of the retrieved certificate in req.Retrieved
+func setRetrieved(req *certificate.Request, certificateID string, pcc *certificate.PEMCollection) {
+	retrieved := &certificate.RetrievedCertificate{CertID: certificateID, PickupID: req.PickupID}
+	if b, _ := pem.Decode([]byte(pcc.Certificate)); b != nil {
+		retrieved.Thumbprint = certThumbprint(b.Bytes)
+	}
+	req.Retrieved = retrieved
+}
+
 func parseApplicationDetailsResult(httpStatusCode int, httpStatus string, body []byte) (*ApplicationDetails, error) {
 	switch httpStatusCode {
 	case http.StatusOK:
 		return parseApplicationDetailsData(body)
-	case http.StatusBadRequest:
+	case http.StatusBadRequest, http.StatusNotFound:
 		return nil, verror.ApplicationNotFoundError
 	default:
-		respErrors, err := parseResponseErrors(body)
-		if err != nil {
-			return nil, err
-		}
-
-		respError := fmt.Sprintf("Unexpected status code on Venafi Cloud application read. Status: %s\n", httpStatus)
-		for _, e := range respErrors {
-			if e.Code == 10051 {
-				return nil, verror.ApplicationNotFoundError
-			}
-			respError += fmt.Sprintf("Error Code: %d Error: %s\n", e.Code, e.Message)
+		if hasErrorCode(body, 10051) {
+			return nil, verror.ApplicationNotFoundError
 		}
-		return nil, fmt.Errorf("%w: %v", verror.ServerError, respError)
+		return nil, fmt.Errorf("failed to read application: %w", mapStatusToError(httpStatusCode, body))
 	}
 }
 
 func parseApplicationDetailsData(b []byte) (*ApplicationDetails, error) {
 	var data ApplicationDetails
-	err := json.Unmarshal(b, &data)
+	err := unmarshalJSON(b, &data)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", verror.ServerError, err)
 	}
@@ -472,18 +764,20 @@ func (z *cloudZone) getTemplateAlias() string {
 	return z.templateAlias
 }
 
-func (z *cloudZone) parseZone() error {
-	if z.zone == "" {
-		return fmt.Errorf("zone not specified")
+// validate parses the zone and returns a verror.UserDataError with the expected format if it's malformed
+func (z *cloudZone) validate() error {
+	if err := z.parseZone(); err != nil {
+		return fmt.Errorf("%w: %v: %q, expected \"application\\template alias\"", verror.UserDataError, err, z.zone)
 	}
+	return nil
+}
 
-	segments := strings.Split(z.zone, "\\")
-	if len(segments) > 2 || len(segments) < 2 {
-		return fmt.Errorf("invalid zone format")
+func (z *cloudZone) parseZone() error {
+	parsed, err := ParseZone(z.zone)
+	if err != nil {
+		return err
 	}
-
-	z.appName = segments[0]
-	z.templateAlias = segments[1]
-
+	z.appName = parsed.Application
+	z.templateAlias = parsed.TemplateAlias
 	return nil
 }
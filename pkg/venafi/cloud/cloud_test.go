@@ -17,9 +17,15 @@
 package cloud
 
 import (
-	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"crypto/x509"
+	"encoding/pem"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
 )
 
 var (
@@ -139,3 +145,102 @@ func TestParseCertificateRetrieveResponse(t *testing.T) {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 }
+
+const mockZone = "App\\Template"
+
+var successGetAppDetails = []byte(`{"id":"a1b2c3d4-0000-11eb-0000-000000000000","certificateIssuingTemplateAliasIdMap":{"Template":"t1t2t3t4-0000-11eb-0000-000000000000"}}`)
+
+// newMockConnector starts a TLS test server with the given handler and returns an "authenticated" connector pointed to it.
+// The caller is responsible for closing the server.
+func newMockConnector(t *testing.T, handler http.HandlerFunc) (*Connector, *httptest.Server) {
+	server := httptest.NewTLSServer(handler)
+	conn, err := NewConnector(server.URL, mockZone, false, nil)
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	conn.SetHTTPClient(server.Client())
+	conn.apiKey = "mock-api-key"
+	conn.user = &userDetails{User: &user{ID: "mock-user"}, Company: &company{ID: "mock-company"}}
+	conn.companyID = "mock-company"
+	conn.SetRetryBaseDelay(time.Millisecond)
+	return conn, server
+}
+
+func newTestCSR(t *testing.T, cn string, dnsNames ...string) *x509.CertificateRequest {
+	req := certificate.Request{}
+	req.Subject.CommonName = cn
+	req.DNSNames = dnsNames
+	if err := req.GeneratePrivateKey(); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if err := req.GenerateCSR(); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	b, _ := pem.Decode(req.GetCSR())
+	csr, err := x509.ParseCertificateRequest(b.Bytes)
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	return csr
+}
+
+func TestMockWithTrust(t *testing.T) {
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(successGetUserAccount)
+	})
+	defer server.Close()
+
+	serverCA := x509.NewCertPool()
+	serverCA.AddCert(server.Certificate())
+	trusted := conn.WithTrust(serverCA)
+	untrusted := conn.WithTrust(x509.NewCertPool())
+
+	if trusted.client == untrusted.client || trusted.client == conn.client {
+		t.Fatalf("expected cloned connectors to have their own http clients")
+	}
+	err := trusted.Authenticate(&endpoint.Authentication{APIKey: "mock-api-key"})
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	err = untrusted.Authenticate(&endpoint.Authentication{APIKey: "mock-api-key"})
+	if err == nil {
+		t.Fatalf("err nil, expected TLS error for the connector with an empty trust pool")
+	}
+	if conn.trust != nil {
+		t.Fatalf("original connector trust pool should not be changed")
+	}
+}
+
+func TestMockDefaultHTTPClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(headerNameAPIKey) == "slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		_, _ = w.Write(successGetUserAccount)
+	}))
+	defer server.Close()
+	serverCA := x509.NewCertPool()
+	serverCA.AddCert(server.Certificate())
+	conn, err := NewConnector(server.URL, mockZone, false, serverCA)
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	conn.SetMaxRetries(0)
+
+	// the connector without SetHTTPClient trusts its pool with the default client
+	if err = conn.Authenticate(&endpoint.Authentication{APIKey: "mock-api-key"}); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	client := conn.getHTTPClient()
+	if client.Timeout != defaultHTTPTimeout || conn.getHTTPClient() != client {
+		t.Fatalf("expected the default client to be made once with the default timeout")
+	}
+
+	conn.SetHTTPTimeout(50 * time.Millisecond)
+	if conn.getHTTPClient().Timeout != 50*time.Millisecond {
+		t.Fatalf("expected the default client to be made again with the timeout")
+	}
+	if err = conn.Authenticate(&endpoint.Authentication{APIKey: "slow"}); err == nil {
+		t.Fatalf("err nil, expected the request to time out")
+	}
+}
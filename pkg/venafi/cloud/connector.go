@@ -17,11 +17,14 @@
 package cloud
 
 import (
+	"context"
+	"crypto/tls"
 	"crypto/x509"
 	"encoding/base64"
-	"encoding/json"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	netUrl "net/url"
 	"regexp"
@@ -50,18 +53,54 @@ const (
 	urlResourceCertificateSearch      urlResource = basePath + "certificatesearch"
 	urlResourceTemplate               urlResource = basePath + "applications/%s/certificateissuingtemplates/%s"
 	urlAppDetailsByName               urlResource = basePath + "applications/name/%s"
+	urlAppDetailsByID                 urlResource = basePath + "applications/%s"
 
 	defaultAppName = "Default"
+
+	// validityTolerance is the allowed difference between requested and issued certificate validity
+	validityTolerance = time.Hour
+
+	// the search for an imported certificate starts right away and backs off from the interval up to
+	// maxImportVerifyDelay until the certificate is indexed or the timeout elapses
+	defaultImportVerifyInterval = 100 * time.Millisecond
+	defaultImportVerifyRetries  = 10
+	defaultImportVerifyTimeout  = 10 * time.Second
+	maxImportVerifyDelay        = 2 * time.Second
+
+	// defaultHTTPTimeout bounds the requests of the default HTTP client, see SetHTTPTimeout
+	defaultHTTPTimeout = 30 * time.Second
 )
 
-type condorChainOption string
+// pollInterval is the default delay between attempts to pick up a pending certificate
+var pollInterval = 2 * time.Second
+
+// ChainOrder is the order of the certificate chain in the contents returned by Venafi Cloud
+type ChainOrder string
 
 const (
-	condorChainOptionRootFirst condorChainOption = "ROOT_FIRST"
-	condorChainOptionRootLast  condorChainOption = "EE_FIRST"
+	// ChainOrderRootFirst returns the root certificate first
+	ChainOrderRootFirst ChainOrder = "ROOT_FIRST"
+	// ChainOrderEEFirst returns the end entity certificate first and the root last
+	ChainOrderEEFirst ChainOrder = "EE_FIRST"
 )
 
-// Connector contains the base data needed to communicate with the Venafi Cloud servers
+// ChainOrderFor maps the chain option of a request to the chain order asked from Venafi Cloud.
+// There is no order without the chain or with the root only, so ChainOptionIgnore and ChainOptionRootOnly ask for
+// ChainOrderEEFirst and the chain is dropped or cut to the root in the returned collection.
+func ChainOrderFor(option certificate.ChainOption) ChainOrder {
+	switch option {
+	case certificate.ChainOptionRootFirst:
+		return ChainOrderRootFirst
+	case certificate.ChainOptionIgnore, certificate.ChainOptionRootOnly:
+		return ChainOrderEEFirst
+	default:
+		return ChainOrderEEFirst
+	}
+}
+
+// Connector contains the base data needed to communicate with the Venafi Cloud servers.
+// A connector can serve concurrent calls. Authenticate, LoadSession, SetZone and SetHTTPClient may be called
+// while it's in use, the other setters configure it and must be called before it's shared.
 type Connector struct {
 	baseURL string
 	apiKey  string
@@ -70,12 +109,81 @@ type Connector struct {
 	trust   *x509.CertPool
 	zone    cloudZone
 	client  *http.Client
+	// headers are added to every request made by the connector
+	headers map[string]string
+	// cache is shared with the connector copies, see zoneCache
+	cache *zoneCache
+	// companyID is cached from the user details at Authenticate
+	companyID string
+	// nowFunc is used instead of time.Now when set, so tests can control time. It drives validity checks,
+	// timeouts, cache expiry and rate limits, the waits themselves and context deadlines use the system clock.
+	nowFunc            func() time.Time
+	clockSkewTolerance time.Duration
+	// clockSkew is shared with the connector copies, see detectClockSkew
+	clockSkew          *int64
+	defaultChainOption certificate.ChainOption
+	// limiters are shared with the connector copies, see SetZoneRateLimit
+	limiters       *zoneLimiters
+	importEncoding ImportEncoding
+	onIssued       func(*certificate.PEMCollection) error
+	// credentials are shared with the connector copies, see SetCredentialProvider
+	credentials *credentialCache
+	// failoverURLs are tried in order when the request to baseURL fails, see SetFailoverURLs
+	failoverURLs []string
+	tracer       Tracer
+	// chains are shared with the connector copies, see completeChain
+	chains *issuerChainCache
+	// importVerifyRetries and importVerifyInterval control the search for the certificate after import
+	importVerifyRetries  int
+	importVerifyInterval time.Duration
+	importVerifyTimeout  time.Duration
+	// retrievePollInterval overrides pollInterval when set, see SetRetrievePollInterval
+	retrievePollInterval time.Duration
+	// tlsState is shared with the connector copies, see LastTLSState
+	tlsState *tlsStateHolder
+	signer   RequestSigner
+	// maxRetries and retryBaseDelay control the retries of failed requests, see SetMaxRetries
+	maxRetries     int
+	retryBaseDelay time.Duration
+	// serverTime is shared with the connector copies, see ServerTime
+	serverTime  *int64
+	secretStore SecretStore
+	// ctx bounds the API calls of the connector copy made by WithContext
+	ctx context.Context
+	// rateLimit is shared with the connector copies, see LastRateLimit
+	rateLimit *rateLimitHolder
+	// state guards baseURL, user, apiKey, companyID, zone, the HTTP clients, clientCert and proxy
+	state  *stateLock
+	logger Logger
+	// httpTimeout overrides defaultHTTPTimeout when set
+	httpTimeout time.Duration
+	// defaultClient is made on the first request when no client was set with SetHTTPClient
+	defaultClient *http.Client
+	// clientCert is presented to the servers requiring mutual TLS, see SetClientCertificate
+	clientCert *tls.Certificate
+	// proxy is used instead of the proxy of the environment when set, see SetProxy
+	proxy *netUrl.URL
+	// callerClient is the client set with SetHTTPClient, client is made from it with the transport options
+	callerClient *http.Client
+	// outboundIPs is shared with the connector copies, see outboundIP
+	outboundIPs *outboundIPHolder
 }
 
-// NewConnector creates a new Venafi Cloud Connector object used to communicate with Venafi Cloud
+// NewConnector creates a new Venafi Cloud Connector object used to communicate with Venafi Cloud.
+// The zone may be empty and set later with SetZone, otherwise it must have the "application\\template alias" form.
 func NewConnector(url string, zone string, verbose bool, trust *x509.CertPool) (*Connector, error) {
-	cZone := cloudZone{zone: zone}
-	c := Connector{verbose: verbose, trust: trust, zone: cZone}
+	cZone := cloudZone{zone: strings.TrimSpace(zone)}
+	if cZone.zone != "" {
+		if err := cZone.validate(); err != nil {
+			return nil, err
+		}
+	}
+	c := Connector{verbose: verbose, trust: trust, zone: cZone, cache: newZoneCache(0),
+		clockSkewTolerance: defaultClockSkewTolerance, clockSkew: new(int64), limiters: newZoneLimiters(),
+		chains: newIssuerChainCache(), importVerifyInterval: defaultImportVerifyInterval,
+		importVerifyRetries: defaultImportVerifyRetries, importVerifyTimeout: defaultImportVerifyTimeout, tlsState: &tlsStateHolder{},
+		maxRetries: defaultMaxRetries, retryBaseDelay: defaultRetryBaseDelay, serverTime: new(int64),
+		rateLimit: &rateLimitHolder{}, state: &stateLock{}, outboundIPs: &outboundIPHolder{}}
 
 	var err error
 	c.baseURL, err = normalizeURL(url)
@@ -85,7 +193,24 @@ func NewConnector(url string, zone string, verbose bool, trust *x509.CertPool) (
 	return &c, nil
 }
 
-//normalizeURL allows overriding the default URL used to communicate with Venafi Cloud
+// SetFailoverURLs sets alternate API URLs. A GET request failing with a network error or a 5xx status
+// is sent again to the next URL, other requests only when they failed before reaching the server, so
+// a certificate isn't requested twice. Authentication, headers and trust apply to all of them.
+func (c *Connector) SetFailoverURLs(urls []string) error {
+	normalized := make([]string, 0, len(urls))
+	for _, u := range urls {
+		n, err := normalizeURL(u)
+		if err != nil {
+			return err
+		}
+		normalized = append(normalized, n)
+	}
+	c.failoverURLs = normalized
+	return nil
+}
+
+//normalizeURL allows overriding the default URL used to communicate with Venafi Cloud.
+//An empty URL is the default API URL and a bare host name gets the https scheme.
 func normalizeURL(url string) (normalizedURL string, err error) {
 	if url == "" {
 		url = apiURL
@@ -101,23 +226,85 @@ func normalizeURL(url string) (normalizedURL string, err error) {
 	if !strings.HasSuffix(modified, "/") {
 		modified = modified + "/"
 	}
+	if strings.Contains(strings.TrimPrefix(modified, "https://"), "://") {
+		return "", fmt.Errorf("%w: invalid base URL %q: only http and https schemes are supported", verror.UserDataError, url)
+	}
+	u, err := netUrl.Parse(modified)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid base URL %q: %v", verror.UserDataError, url, err)
+	}
+	if !validHost(u.Hostname()) || strings.HasSuffix(u.Host, ":") {
+		return "", fmt.Errorf("%w: invalid base URL %q: invalid host %q", verror.UserDataError, url, u.Host)
+	}
+	if u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("%w: invalid base URL %q: user info, query and fragment are not allowed", verror.UserDataError, url)
+	}
 	normalizedURL = modified
 	return normalizedURL, nil
 }
 
+var hostnameRegexp = regexp.MustCompile(`^[a-z0-9_]([a-z0-9_.-]*[a-z0-9_])?$`)
+
+// validHost reports whether host is an IP address or a syntactically valid host name
+func validHost(host string) bool {
+	if net.ParseIP(host) != nil {
+		return true
+	}
+	return len(host) <= 253 && hostnameRegexp.MatchString(host) && !strings.Contains(host, "..")
+}
+
+// SetZone sets the zone of the connector. Changing the zone invalidates the cached application details and
+// templates of the connector, copies of it made before keep sharing the previous cache.
+// A malformed zone makes the calls using it fail with verror.UserDataError, use ParseZone to check it beforehand.
 func (c *Connector) SetZone(z string) {
-	cZone := cloudZone{zone: z}
+	cZone := cloudZone{zone: strings.TrimSpace(z)}
+	if err := cZone.validate(); err != nil && cZone.zone != "" {
+		c.getLogger().Errorf("warning: %v", err)
+	}
+	c.state.lock()
+	defer c.state.unlock()
+	if cZone.zone != c.zone.zone && c.cache != nil {
+		// the cache is replaced rather than emptied, as it's shared with the copies of the connector
+		c.cache = newZoneCache(c.cache.ttl)
+	}
 	c.zone = cZone
 }
 
+// SetDefaultChainOption sets the chain option used by RetrieveCertificate when the request leaves it unset.
+// ChainOptionRootLast is the zero value of ChainOption, so requests with it use the connector default.
+func (c *Connector) SetDefaultChainOption(option certificate.ChainOption) {
+	c.defaultChainOption = option
+}
+
+// SetOnIssued sets a callback invoked by RequestAndRetrieve with the issued certificate, e.g. to deploy it.
+// An error returned by the callback is returned by RequestAndRetrieve.
+func (c *Connector) SetOnIssued(onIssued func(*certificate.PEMCollection) error) {
+	c.onIssued = onIssued
+}
+
 func (c *Connector) GetType() endpoint.ConnectorType {
 	return endpoint.ConnectorTypeCloud
 }
 
-// Ping attempts to connect to the Venafi Cloud API and returns an errror if it cannot
+// Ping attempts to connect to the Venafi Cloud API and returns an errror if it cannot.
+// It doesn't require authentication: network and TLS failures and server errors are reported as
+// verror.ServerTemporaryUnavailableError, while rejected credentials still mean the API is reachable.
 func (c *Connector) Ping() (err error) {
-
-	return nil
+	statusCode, _, body, err := c.request("GET", c.getURL(urlResourceUserAccounts), nil, true)
+	if err != nil {
+		if errors.Is(err, verror.ServerUnavailableError) {
+			return fmt.Errorf("%w: %v", verror.ServerTemporaryUnavailableError, err)
+		}
+		return err
+	}
+	switch {
+	case statusCode == http.StatusOK, statusCode == http.StatusUnauthorized, statusCode == http.StatusForbidden:
+		// the server answers, credentials are checked by Authenticate
+		return nil
+	default:
+		// 5xx responses map to verror.ServerTemporaryUnavailableError
+		return fmt.Errorf("ping failed: %w", mapStatusToError(statusCode, body))
+	}
 }
 
 // Authenticate authenticates the user with Venafi Cloud using the provided API Key
@@ -125,7 +312,9 @@ func (c *Connector) Authenticate(auth *endpoint.Authentication) (err error) {
 	if auth == nil {
 		return fmt.Errorf("failed to authenticate: missing credentials")
 	}
+	c.state.lock()
 	c.apiKey = auth.APIKey
+	c.state.unlock()
 	url := c.getURL(urlResourceUserAccounts)
 	statusCode, status, body, err := c.request("GET", url, nil, true)
 	if err != nil {
@@ -135,10 +324,34 @@ func (c *Connector) Authenticate(auth *endpoint.Authentication) (err error) {
 	if err != nil {
 		return
 	}
+	if ud.Company == nil || ud.Company.ID == "" {
+		return fmt.Errorf("%w: user details don't contain a company", verror.AuthError)
+	}
+	c.state.lock()
 	c.user = ud
+	c.companyID = ud.Company.ID
+	c.state.unlock()
+	c.cache.invalidate()
 	return
 }
 
+// CompanyID returns the ID of the company the authenticated user belongs to
+func (c *Connector) CompanyID() (string, error) {
+	c.state.rlock()
+	defer c.state.runlock()
+	if c.user == nil || c.companyID == "" {
+		return "", fmt.Errorf("%w: must be authenticated to get the company ID", verror.AuthError)
+	}
+	return c.companyID, nil
+}
+
+func (c *Connector) requireAuthentication(action string) error {
+	if _, err := c.CompanyID(); err != nil {
+		return fmt.Errorf("%w: must be authenticated to %s", verror.AuthError, action)
+	}
+	return nil
+}
+
 func (c *Connector) ReadPolicyConfiguration() (policy *endpoint.Policy, err error) {
 	config, err := c.ReadZoneConfiguration()
 	if err != nil {
@@ -160,39 +373,89 @@ func (c *Connector) ReadZoneConfiguration() (config *endpoint.ZoneConfiguration,
 
 // RequestCertificate submits the CSR to the Venafi Cloud API for processing
 func (c *Connector) RequestCertificate(req *certificate.Request) (requestID string, err error) {
-	if req.CsrOrigin == certificate.ServiceGeneratedCSR {
-		return "", fmt.Errorf("service generated CSR is not supported by Saas service")
+	// all calls below are made by a copy of the connector carrying the request headers
+	c, err = c.withExtraHeaders(req.ExtraHeaders)
+	if err != nil {
+		return "", err
 	}
 
 	url := c.getURL(urlResourceCertificateRequests)
-	if c.user == nil || c.user.Company == nil {
-		return "", fmt.Errorf("must be autheticated to request a certificate")
+	if err = c.requireAuthentication("request a certificate"); err != nil {
+		return "", err
+	}
+	cloudReq, err := c.newCertificateRequest(req)
+	if err != nil {
+		return "", err
 	}
 
-	ipAddr := endpoint.LocalIP
-	origin := endpoint.SDKName
-	for _, f := range req.CustomFields {
-		if f.Type == certificate.CustomFieldOrigin {
-			origin = f.Value
-		}
+	if err = c.limiters.wait(c.context(), c.currentZone().String(), c.now()); err != nil {
+		return "", fmt.Errorf("%w: waiting for the rate limit of zone %s", err, c.currentZone())
 	}
+	statusCode, status, body, err := c.request("POST", url, cloudReq)
 
-	appDetails, err := c.getAppDetailsByName(c.zone.getApplicationName())
 	if err != nil {
 		return "", err
 	}
-	templateId := appDetails.CitAliasToIdMap[c.zone.getTemplateAlias()]
+	cr, err := parseCertificateRequestResult(statusCode, status, body)
+	if err != nil {
+		return "", err
+	}
+	if len(cr.CertificateRequests) == 0 {
+		return "", fmt.Errorf("%w: certificate request response has no requests: %s", verror.ServerBadDataResponce, bodySnippet(body))
+	}
+	requestID = cr.CertificateRequests[0].ID
+	req.PickupID = requestID
+	c.addWarnings(req, warningMessages(cr.Warnings)...)
+	return requestID, nil
+}
+
+// newCertificateRequest builds the body of the certificate request for the zone
+func (c *Connector) newCertificateRequest(req *certificate.Request) (*certificateRequest, error) {
+	ipAddr, err := c.clientIdentifier(req.ClientIP)
+	if err != nil {
+		return nil, err
+	}
+	origin := clientOrigin(req.Origin, req.CustomFields)
+
+	if len(req.GetCSR()) == 0 && req.ParsedCSR != nil {
+		if err := req.SetParsedCSR(req.ParsedCSR); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.checkRequestPolicy(req); err != nil {
+		return nil, err
+	}
+
+	appDetails, err := c.zoneAppDetails()
+	if err != nil {
+		return nil, err
+	}
+	zone := c.currentZone()
+	templateId := appDetails.CitAliasToIdMap[zone.getTemplateAlias()]
 
 	cloudReq := certificateRequest{
 		CSR:           string(req.GetCSR()),
 		ApplicationId: appDetails.ApplicationId,
 		TemplateId:    templateId,
-		ApiClientInformation: certificateRequestClientInfo{
+		ApiClientInformation: &certificateRequestClientInfo{
 			Type:       origin,
 			Identifier: ipAddr,
 		},
 	}
 
+	if req.CsrOrigin == certificate.ServiceGeneratedCSR {
+		template, err := c.getTemplateByID()
+		if err != nil {
+			return nil, err
+		}
+		if !template.KeyGeneratedByVenafiAllowed {
+			return nil, fmt.Errorf("%w: issuing template %s doesn't allow service generated CSR", verror.UserDataError, zone.getTemplateAlias())
+		}
+		cloudReq.CSR = ""
+		cloudReq.IsVaaSGenerated = true
+		cloudReq.CsrAttributes = newCSRAttributes(req)
+	}
+
 	if req.Location != nil {
 		workload := req.Location.Workload
 		if workload == "" {
@@ -215,18 +478,46 @@ func (c *Connector) RequestCertificate(req *certificate.Request) (requestID stri
 		cloudReq.ValidityPeriod = validityHoursStr
 	}
 
-	statusCode, status, body, err := c.request("POST", url, cloudReq)
+	return &cloudReq, nil
+}
 
-	if err != nil {
-		return "", err
+// clientOrigin returns the origin reported in the API client information: the explicit origin when set,
+// then the value of a CustomFieldOrigin custom field, endpoint.SDKName otherwise
+func clientOrigin(origin string, fields []certificate.CustomField) string {
+	if origin != "" {
+		return origin
 	}
-	cr, err := parseCertificateRequestResult(statusCode, status, body)
+	origin = endpoint.SDKName
+	for _, f := range fields {
+		if f.Type == certificate.CustomFieldOrigin {
+			origin = f.Value
+		}
+	}
+	return origin
+}
+
+// addWarnings stores non-fatal issues on the request so the caller can report them
+func (c *Connector) addWarnings(req *certificate.Request, warnings ...string) {
+	for _, w := range warnings {
+		c.getLogger().Infof("warning: %s", w)
+		req.Warnings = append(req.Warnings, w)
+	}
+}
+
+// GetRequestCSR returns the PEM-encoded CSR that was submitted with the certificate request
+func (c *Connector) GetRequestCSR(pickupID string) (csrPEM []byte, err error) {
+	certStatus, err := c.getCertificateStatus(pickupID)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	requestID = cr.CertificateRequests[0].ID
-	req.PickupID = requestID
-	return requestID, nil
+	if certStatus.CertificateSigningRequest == "" {
+		return nil, fmt.Errorf("%w: certificate request %s has no stored CSR", verror.VcertError, pickupID)
+	}
+	csrPEM = []byte(certStatus.CertificateSigningRequest)
+	if b, _ := pem.Decode(csrPEM); b == nil || !strings.HasSuffix(b.Type, "CERTIFICATE REQUEST") {
+		return nil, fmt.Errorf("%w: certificate request %s has invalid CSR", verror.ServerBadDataResponce, pickupID)
+	}
+	return csrPEM, nil
 }
 
 func (c *Connector) getCertificateStatus(requestID string) (certStatus *certificateStatus, err error) {
@@ -238,30 +529,59 @@ func (c *Connector) getCertificateStatus(requestID string) (certStatus *certific
 	}
 	if statusCode == http.StatusOK {
 		certStatus = &certificateStatus{}
-		err = json.Unmarshal(body, certStatus)
+		err = unmarshalJSON(body, certStatus)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse certificate request status response: %s", err)
 		}
 		return
 	}
-	respErrors, err := parseResponseErrors(body)
-	if err == nil {
-		respError := fmt.Sprintf("Unexpected status code on Venafi Cloud certificate search. Status: %d\n", statusCode)
-		for _, e := range respErrors {
-			respError += fmt.Sprintf("Error Code: %d Error: %s\n", e.Code, e.Message)
+	return nil, fmt.Errorf("failed to read certificate request status: %w", mapStatusToError(statusCode, body))
+}
+
+// RequestAndRetrieve submits the request, waits for the certificate to be issued and retrieves it.
+// req.Timeout should be set to wait for issuance. The callback set with SetOnIssued is invoked with the retrieved certificate.
+func (c *Connector) RequestAndRetrieve(req *certificate.Request) (*certificate.PEMCollection, error) {
+	if _, err := c.RequestCertificate(req); err != nil {
+		return nil, err
+	}
+	pcc, err := c.RetrieveCertificate(req)
+	if err != nil {
+		return nil, err
+	}
+	if c.onIssued != nil {
+		if err := c.onIssued(pcc); err != nil {
+			return pcc, fmt.Errorf("post-issue callback failed: %w", err)
 		}
-		return nil, fmt.Errorf(respError)
 	}
+	return pcc, nil
+}
 
-	return nil, fmt.Errorf("unexpected status code on Venafi Cloud certificate search. Status: %d", statusCode)
-
+// RetrieveIfValid retrieves the existing certificate for the request and returns it only if it stays valid for
+// at least minRemaining. Otherwise verror.CertificateRenewalNeededError is returned and the certificate should be renewed.
+func (c *Connector) RetrieveIfValid(req *certificate.Request, minRemaining time.Duration) (*certificate.PEMCollection, error) {
+	pcc, err := c.RetrieveCertificate(req)
+	if err != nil {
+		return nil, err
+	}
+	b, _ := pem.Decode([]byte(pcc.Certificate))
+	if b == nil {
+		return nil, fmt.Errorf("%w: failed to decode retrieved certificate", verror.ServerBadDataResponce)
+	}
+	cert, err := x509.ParseCertificate(b.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to parse retrieved certificate: %v", verror.ServerBadDataResponce, err)
+	}
+	if cert.NotAfter.Before(c.serverNow().Add(minRemaining)) {
+		return nil, fmt.Errorf("%w: certificate %s expires at %s", verror.CertificateRenewalNeededError, cert.Subject.CommonName, cert.NotAfter.Format(time.RFC3339))
+	}
+	return pcc, nil
 }
 
 // RetrieveCertificate retrieves the certificate for the specified ID
 func (c *Connector) RetrieveCertificate(req *certificate.Request) (certificates *certificate.PEMCollection, err error) {
-
-	if req.FetchPrivateKey {
-		return nil, fmt.Errorf("failed to retrieve private key from Venafi Cloud service: not supported")
+	c, err = c.withExtraHeaders(req.ExtraHeaders)
+	if err != nil {
+		return nil, err
 	}
 	if req.PickupID == "" && req.CertID == "" && req.Thumbprint != "" {
 		// search cert by Thumbprint and fill pickupID
@@ -295,7 +615,7 @@ func (c *Connector) RetrieveCertificate(req *certificate.Request) (certificates
 		req.PickupID = certificateRequestId
 	}
 
-	startTime := time.Now()
+	startTime := c.now()
 	//Wait for certificate to be issued by checking it's PickupID
 	//If certID is filled then certificate should be already issued.
 	var certificateId string
@@ -306,7 +626,15 @@ func (c *Connector) RetrieveCertificate(req *certificate.Request) (certificates
 			}
 			certStatus, err := c.getCertificateStatus(req.PickupID)
 			if err != nil {
-				return nil, fmt.Errorf("unable to retrieve: %s", err)
+				// a transient failure the retries of the request didn't recover from doesn't end waiting for the certificate
+				if !isTransientError(err) || req.Timeout == 0 || c.now().After(startTime.Add(req.Timeout)) {
+					return nil, fmt.Errorf("unable to retrieve: %w", err)
+				}
+				c.getLogger().Infof("Failed to read the status of %s, polling again: %s", req.PickupID, err)
+				if err = c.sleep(c.getPollInterval()); err != nil {
+					return nil, err
+				}
+				continue
 			}
 			if certStatus.Status == "ISSUED" {
 				certificateId = certStatus.CertificateIdsList[0]
@@ -318,18 +646,23 @@ func (c *Connector) RetrieveCertificate(req *certificate.Request) (certificates
 			if req.Timeout == 0 {
 				return nil, endpoint.ErrCertificatePending{CertificateID: req.PickupID, Status: certStatus.Status}
 			}
-			if time.Now().After(startTime.Add(req.Timeout)) {
+			if c.now().After(startTime.Add(req.Timeout)) {
+				if req.ReturnPendingOnTimeout {
+					return nil, endpoint.ErrCertificatePending{CertificateID: req.PickupID, Status: certStatus.Status}
+				}
 				return nil, endpoint.ErrRetrieveCertificateTimeout{CertificateID: req.PickupID}
 			}
 			// fmt.Printf("pending... %s\n", status.Status)
-			time.Sleep(2 * time.Second)
+			if err = c.sleep(c.getPollInterval()); err != nil {
+				return nil, err
+			}
 		}
 	} else {
 		certificateId = req.CertID
 	}
 
-	if c.user == nil || c.user.Company == nil {
-		return nil, fmt.Errorf("must be autheticated to retieve certificate")
+	if err = c.requireAuthentication("retrieve a certificate"); err != nil {
+		return nil, err
 	}
 
 	url := c.getURL(urlResourceCertificateRetrievePem)
@@ -344,40 +677,67 @@ func (c *Connector) RetrieveCertificate(req *certificate.Request) (certificates
 		if statusCode != http.StatusOK {
 			return nil, fmt.Errorf("failed to retrieve certificate. StatusCode: %d -- Status: %s -- Server Data: %s", statusCode, status, body)
 		}
-		return newPEMCollectionFromResponse(body, certificate.ChainOptionIgnore)
+		certificates, err = newPEMCollectionFromResponse(body, certificate.ChainOptionIgnore)
+		if err == nil && req.ExpectedThumbprint != "" {
+			if err = checkThumbprint(certificates.Certificate, req.ExpectedThumbprint); err != nil {
+				return nil, err
+			}
+		}
+		if err == nil && req.FetchPrivateKey {
+			if certificates.PrivateKey, err = c.fetchPrivateKey(certificateId, req.KeyPassword); err != nil {
+				return nil, err
+			}
+		}
+		if err == nil {
+			setRetrieved(req, certificateId, certificates)
+		}
+		return certificates, err
 	case req.PickupID != "":
 		url += "?chainOrder=%s&format=PEM"
-		switch req.ChainOption {
-		case certificate.ChainOptionRootFirst:
-			url = fmt.Sprintf(url, condorChainOptionRootFirst)
-		default:
-			url = fmt.Sprintf(url, condorChainOptionRootLast)
+		chainOption := req.ChainOption
+		if chainOption == certificate.ChainOptionRootLast {
+			chainOption = c.defaultChainOption
 		}
-		statusCode, status, body, err := c.request("GET", url, nil)
+		url = fmt.Sprintf(url, ChainOrderFor(chainOption))
+		statusCode, _, body, err := c.request("GET", url, nil)
 		if err != nil {
 			return nil, err
 		}
 		if statusCode == http.StatusOK {
-			certificates, err = newPEMCollectionFromResponse(body, req.ChainOption)
+			certificates, err = newPEMCollectionFromResponse(body, chainOption)
 			if err != nil {
 				return nil, err
 			}
 			err = req.CheckCertificate(certificates.Certificate)
+			if err != nil {
+				return certificates, err
+			}
+			if req.ExpectedThumbprint != "" {
+				if err = checkThumbprint(certificates.Certificate, req.ExpectedThumbprint); err != nil {
+					return nil, err
+				}
+			}
+			if err = c.completeChain(req, certificates, chainOption); err != nil {
+				return nil, err
+			}
+			if req.FetchPrivateKey {
+				if certificates.PrivateKey, err = c.fetchPrivateKey(certificateId, req.KeyPassword); err != nil {
+					return nil, err
+				}
+			}
+			setRetrieved(req, certificateId, certificates)
+			warnings, err := req.IssuedCertificateWarnings(certificates.Certificate, validityTolerance)
+			c.addWarnings(req, warnings...)
 			return certificates, err
 		} else if statusCode == http.StatusConflict { // Http Status Code 409 means the certificate has not been signed by the ca yet.
 			return nil, endpoint.ErrCertificatePending{CertificateID: req.PickupID}
 		} else {
-			return nil, fmt.Errorf("failed to retrieve certificate. StatusCode: %d -- Status: %s", statusCode, status)
+			return nil, fmt.Errorf("failed to retrieve certificate: %w", mapStatusToError(statusCode, body))
 		}
 	}
 	return nil, fmt.Errorf("couldn't retrieve certificate because both PickupID and CertId are empty")
 }
 
-// RevokeCertificate attempts to revoke the certificate
-func (c *Connector) RevokeCertificate(revReq *certificate.RevocationRequest) (err error) {
-	return fmt.Errorf("not supported by endpoint")
-}
-
 // RenewCertificate attempts to renew the certificate
 func (c *Connector) RenewCertificate(renewReq *certificate.RenewalRequest) (requestID string, err error) {
 
@@ -453,8 +813,8 @@ func (c *Connector) RenewCertificate(renewReq *certificate.RenewalRequest) (requ
 
 	/* 4th step is to send renewal request */
 	url := c.getURL(urlResourceCertificateRequests)
-	if c.user == nil || c.user.Company == nil {
-		return "", fmt.Errorf("must be autheticated to request a certificate")
+	if err = c.requireAuthentication("request a certificate"); err != nil {
+		return "", err
 	}
 
 	req := certificateRequest{
@@ -463,7 +823,7 @@ func (c *Connector) RenewCertificate(renewReq *certificate.RenewalRequest) (requ
 		TemplateId:            templateId,
 	}
 
-	if renewReq.CertificateRequest.Location != nil {
+	if renewReq.CertificateRequest != nil && renewReq.CertificateRequest.Location != nil {
 		workload := renewReq.CertificateRequest.Location.Workload
 		if workload == "" {
 			workload = defaultAppName
@@ -483,8 +843,14 @@ func (c *Connector) RenewCertificate(renewReq *certificate.RenewalRequest) (requ
 		req.CSR = string(renewReq.CertificateRequest.GetCSR())
 		req.ReuseCSR = false
 	} else {
+		template, err := c.fetchTemplateByID(templateId)
+		if err != nil {
+			return "", fmt.Errorf("failed to renew certificate: %w", err)
+		}
+		if !template.KeyReuse {
+			return "", fmt.Errorf("%w: issuing template %s doesn't allow reusing the CSR, a new CSR must be provided in the request", verror.UserDataError, template.Name)
+		}
 		req.ReuseCSR = true
-		return "", fmt.Errorf("reuseCSR option is not currently available for Renew Certificate operation. A new CSR must be provided in the request")
 	}
 	statusCode, status, body, err := c.request("POST", url, req)
 	if err != nil {
@@ -495,9 +861,30 @@ func (c *Connector) RenewCertificate(renewReq *certificate.RenewalRequest) (requ
 	if err != nil {
 		return "", fmt.Errorf("failed to renew certificate: %s", err)
 	}
+	if len(cr.CertificateRequests) == 0 {
+		return "", fmt.Errorf("%w: certificate renewal response has no requests: %s", verror.ServerBadDataResponce, bodySnippet(body))
+	}
+	if req.ReuseCSR && renewReq.CertificateRequest != nil && previousRequest.CertificateSigningRequest != "" {
+		// RetrieveCertificate checks that the renewed certificate is bound to the original key
+		if err = renewReq.CertificateRequest.SetCSR([]byte(previousRequest.CertificateSigningRequest)); err != nil {
+			return "", fmt.Errorf("%w: certificate request %s has invalid CSR: %v", verror.ServerBadDataResponce, certificateRequestId, err)
+		}
+	}
 	return cr.CertificateRequests[0].ID, nil
 }
 
+// SearchCertificates runs the search request, e.g. to filter certificates by issuer, validity or custom fields.
+// Results are paged by req.Paging.
+func (c *Connector) SearchCertificates(req *SearchRequest) (*CertificateSearchResponse, error) {
+	if req == nil {
+		return nil, fmt.Errorf("%w: search request can not be nil", verror.UserDataError)
+	}
+	if err := c.requireAuthentication("search certificates"); err != nil {
+		return nil, err
+	}
+	return c.searchCertificates(req)
+}
+
 func (c *Connector) searchCertificates(req *SearchRequest) (*CertificateSearchResponse, error) {
 
 	var err error
@@ -515,9 +902,7 @@ func (c *Connector) searchCertificates(req *SearchRequest) (*CertificateSearchRe
 }
 
 func (c *Connector) searchCertificatesByFingerprint(fp string) (*CertificateSearchResponse, error) {
-	fp = strings.Replace(fp, ":", "", -1)
-	fp = strings.Replace(fp, ".", "", -1)
-	fp = strings.ToUpper(fp)
+	fp = normalizeFingerprint(fp)
 	req := &SearchRequest{
 		Expression: &Expression{
 			Operands: []Operand{
@@ -544,9 +929,23 @@ func (c *Connector) searchCertificatesByFingerprint(fp string) (*CertificateSear
 
 */
 type managedCertificate struct {
-	Id                   string `json:"id"`
-	CompanyId            string `json:"companyId"`
-	CertificateRequestId string `json:"certificateRequestId"`
+	Id                       string                     `json:"id"`
+	CompanyId                string                     `json:"companyId"`
+	CertificateRequestId     string                     `json:"certificateRequestId"`
+	CertificateUsageMetadata []certificateUsageMetadata `json:"certificateUsageMetadata"`
+}
+
+// GetCertificateInstallations returns where the certificate is installed, as recorded from certificate.Request.Location
+func (c *Connector) GetCertificateInstallations(certID string) ([]certificate.Location, error) {
+	cert, err := c.getCertificate(certID)
+	if err != nil {
+		return nil, err
+	}
+	locations := make([]certificate.Location, 0, len(cert.CertificateUsageMetadata))
+	for _, m := range cert.CertificateUsageMetadata {
+		locations = append(locations, certificate.Location{Instance: m.NodeName, Workload: m.AppName})
+	}
+	return locations, nil
 }
 
 func (c *Connector) getCertificate(certificateId string) (*managedCertificate, error) {
@@ -561,140 +960,310 @@ func (c *Connector) getCertificate(certificateId string) (*managedCertificate, e
 	switch statusCode {
 	case http.StatusOK:
 		var res = &managedCertificate{}
-		err = json.Unmarshal(body, res)
+		err = unmarshalJSON(body, res)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse search results: %s, body: %s", err, body)
 		}
 		return res, nil
 	default:
-		if body != nil {
-			respErrors, err := parseResponseErrors(body)
-			if err == nil {
-				respError := fmt.Sprintf("unexpected status code on Venafi Cloud certificate search. Status: %d\n", statusCode)
-				for _, e := range respErrors {
-					respError += fmt.Sprintf("Error Code: %d Error: %s\n", e.Code, e.Message)
-				}
-				return nil, fmt.Errorf(respError)
-			}
-		}
-		return nil, fmt.Errorf("unexpected status code on Venafi Cloud certificate search. Status: %d", statusCode)
+		return nil, fmt.Errorf("failed to read certificate: %w", mapStatusToError(statusCode, body))
 	}
 }
 
 func (c *Connector) ImportCertificate(req *certificate.ImportRequest) (*certificate.ImportResponse, error) {
-	pBlock, _ := pem.Decode([]byte(req.CertificateData))
-	if pBlock == nil {
+	certs := certificateBlocks([]byte(req.CertificateData))
+	if len(certs) == 0 {
 		return nil, fmt.Errorf("%w can`t parse certificate", verror.UserDataError)
 	}
+	if req.PrivateKeyData != "" {
+		if c.importEncoding == ImportEncodingRawPEM {
+			return nil, fmt.Errorf("%w: private key can't be imported with the raw PEM encoding", verror.UserDataError)
+		}
+		if err := checkImportPrivateKey(req.PrivateKeyData, req.Password, certs[0]); err != nil {
+			return nil, err
+		}
+	}
 	zone := req.PolicyDN
 	if zone == "" {
-		appDetails, err := c.getAppDetailsByName(c.zone.getApplicationName())
+		appDetails, err := c.zoneAppDetails()
 		if err != nil {
 			return nil, err
 		}
 		zone = appDetails.ApplicationId
 	}
-	ipAddr := endpoint.LocalIP
-	origin := endpoint.SDKName
-	for _, f := range req.CustomFields {
-		if f.Type == certificate.CustomFieldOrigin {
-			origin = f.Value
+	ipAddr := c.outboundIP()
+	origin := clientOrigin("", req.CustomFields)
+	fingerprint := certThumbprint(certs[0])
+	info := importRequestCertInfo{
+		Certificate:    base64.StdEncoding.EncodeToString(certs[0]),
+		ApplicationIds: []string{zone},
+		ApiClientInformation: apiClientInformation{
+			Type:       origin,
+			Identifier: ipAddr,
+		},
+		PrivateKey: req.PrivateKeyData,
+	}
+	if req.PrivateKeyData != "" {
+		info.PrivateKeyPassphrase = req.Password
+	}
+	for _, issuer := range certs[1:] {
+		info.IssuerCertificates = append(info.IssuerCertificates, base64.StdEncoding.EncodeToString(issuer))
+	}
+	request := importRequest{Certificates: []importRequestCertInfo{info}}
+
+	var r *importResponse
+	var err error
+	if c.importEncoding == ImportEncodingRawPEM {
+		r, err = c.postRawImport(zone, req.CertificateData)
+	} else {
+		r, err = c.postImportRequest(request)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err = importedCertInfo(r, fingerprint); err != nil {
+		return nil, err
+	}
+	// the imported certificate is searchable only after it's indexed
+	var foundCert *CertificateSearchResponse
+	deadline := c.now().Add(c.importVerifyTimeout)
+	delay := c.importVerifyInterval
+	for attempt := 0; ; attempt++ {
+		foundCert, err = c.searchCertificatesByFingerprint(fingerprint)
+		if err != nil {
+			return nil, err
+		}
+		if len(foundCert.Certificates) == 1 {
+			break
+		}
+		if attempt >= c.importVerifyRetries || c.now().Add(delay).After(deadline) {
+			return nil, fmt.Errorf("%w certificate has been imported but could not be found on platform after that", verror.ServerError)
+		}
+		if err = c.sleep(delay); err != nil {
+			return nil, err
+		}
+		if delay *= 2; delay > maxImportVerifyDelay {
+			delay = maxImportVerifyDelay
 		}
 	}
-	base64.StdEncoding.EncodeToString(pBlock.Bytes)
-	fingerprint := certThumbprint(pBlock.Bytes)
-	request := importRequest{
-		Certificates: []importRequestCertInfo{
-			{
-				Certificate:    base64.StdEncoding.EncodeToString(pBlock.Bytes),
-				ApplicationIds: []string{zone},
-				ApiClientInformation: apiClientInformation{
-					Type:       origin,
-					Identifier: ipAddr,
-				},
-			},
-		},
+	cert := foundCert.Certificates[0]
+	resp := &certificate.ImportResponse{CertificateDN: cert.SubjectCN[0], CertId: cert.Id}
+	return resp, nil
+}
+
+// importedCertInfo picks the information of the certificate with the fingerprint from the import response.
+// Chain and batch imports return an entry for every imported certificate, so entries are matched by fingerprint.
+func importedCertInfo(r *importResponse, fingerprint string) (*importResponseCertInfo, error) {
+	infos := make(map[string]*importResponseCertInfo, len(r.CertificateInformations))
+	for i := range r.CertificateInformations {
+		info := &r.CertificateInformations[i]
+		infos[normalizeFingerprint(info.Fingerprint)] = info
+	}
+	if info, ok := infos[fingerprint]; ok {
+		return info, nil
 	}
+	if len(r.CertificateInformations) == 1 {
+		return &r.CertificateInformations[0], nil
+	}
+	return nil, fmt.Errorf("%w: certificate was not imported on unknown reason", verror.ServerBadDataResponce)
+}
 
+func (c *Connector) postImportRequest(request importRequest) (*importResponse, error) {
 	url := c.getURL(urlResourceCertificates)
-	statusCode, status, body, err := c.request("POST", url, request)
+	statusCode, _, body, err := c.request("POST", url, request)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", verror.ServerTemporaryUnavailableError, err)
 	}
 	var r importResponse
 	switch statusCode {
-	case http.StatusOK, http.StatusCreated, http.StatusAccepted:
-	case http.StatusBadRequest, http.StatusForbidden, http.StatusConflict:
-		return nil, fmt.Errorf("%w: certificate can`t be imported. %d %s %s", verror.ServerBadDataResponce, statusCode, status, string(body))
-	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable:
-		return nil, verror.ServerTemporaryUnavailableError
+	case http.StatusOK, http.StatusCreated, http.StatusAccepted, http.StatusNoContent:
 	default:
-		return nil, verror.ServerError
+		return nil, fmt.Errorf("certificate can`t be imported: %w", mapStatusToError(statusCode, body))
+	}
+	if statusCode == http.StatusNoContent {
+		return &r, nil
 	}
-	err = json.Unmarshal(body, &r)
+	err = unmarshalJSON(body, &r)
 	if err != nil {
 		return nil, fmt.Errorf("%w: can`t unmarshal json response %s", verror.ServerError, err)
-	} else if !(len(r.CertificateInformations) == 1) {
-		return nil, fmt.Errorf("%w: certificate was not imported on unknown reason", verror.ServerBadDataResponce)
 	}
-	time.Sleep(time.Second)
-	foundCert, err := c.searchCertificatesByFingerprint(fingerprint)
+	return &r, nil
+}
+
+// postRawImport uploads the certificate and its chain as PEM (or PKCS#7) instead of base64 DER in JSON.
+// Private keys are never uploaded.
+func (c *Connector) postRawImport(appID string, data string) (*importResponse, error) {
+	var upload []byte
+	contentType := "application/x-pem-file"
+	rest := []byte(data)
+	for {
+		var b *pem.Block
+		b, rest = pem.Decode(rest)
+		if b == nil {
+			break
+		}
+		switch b.Type {
+		case "CERTIFICATE":
+		case "PKCS7":
+			contentType = "application/x-pkcs7-certificates"
+		default:
+			continue
+		}
+		upload = append(upload, pem.EncodeToMemory(b)...)
+	}
+	if len(upload) == 0 {
+		return nil, fmt.Errorf("%w: no certificate found to import", verror.UserDataError)
+	}
+	url := c.getURL(urlResourceCertificates) + "?applicationIds=" + netUrl.QueryEscape(appID)
+	statusCode, _, body, err := c.request("POST", url, rawPayload{contentType: contentType, data: upload})
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", verror.ServerTemporaryUnavailableError, err)
+	}
+	switch statusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusAccepted, http.StatusNoContent:
+	default:
+		return nil, fmt.Errorf("certificate can`t be imported: %w", mapStatusToError(statusCode, body))
 	}
-	if len(foundCert.Certificates) != 1 {
-		return nil, fmt.Errorf("%w certificate has been imported but could not be found on platform after that", verror.ServerError)
+	var r importResponse
+	if statusCode == http.StatusNoContent {
+		return &r, nil
 	}
-	cert := foundCert.Certificates[0]
-	resp := &certificate.ImportResponse{CertificateDN: cert.SubjectCN[0], CertId: cert.Id}
-	return resp, nil
+	err = unmarshalJSON(body, &r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: can`t unmarshal json response %s", verror.ServerError, err)
+	}
+	return &r, nil
+}
+
+// ImportEncoding selects how ImportCertificate uploads certificates
+type ImportEncoding int
+
+const (
+	// ImportEncodingJSON sends the certificate as base64 DER in a JSON body. This is the default.
+	ImportEncodingJSON ImportEncoding = iota
+	// ImportEncodingRawPEM uploads the certificate PEM (or PKCS#7) data with the chain as is, for the API
+	// variants accepting it. It keeps the chain exactly as provided.
+	ImportEncodingRawPEM
+)
+
+// SetImportVerifyRetries sets how many more times ImportCertificate searches for the imported certificate
+// when it's not found yet. The search races the indexing of the certificate, so it has its own budget.
+func (c *Connector) SetImportVerifyRetries(retries int) {
+	c.importVerifyRetries = retries
+}
+
+// SetImportVerifyInterval sets how long ImportCertificate waits before it searches for the imported certificate again.
+// The delay doubles with every search.
+func (c *Connector) SetImportVerifyInterval(interval time.Duration) {
+	c.importVerifyInterval = interval
+}
+
+// SetImportVerifyTimeout sets how long ImportCertificate searches for the imported certificate until it's indexed.
+// The default is 10 seconds, bulk importers may lower it to fail fast.
+func (c *Connector) SetImportVerifyTimeout(timeout time.Duration) {
+	c.importVerifyTimeout = timeout
+}
+
+// SetRetrievePollInterval sets how long RetrieveCertificate waits between checks of a pending request.
+// The default is 2 seconds, a longer interval saves API quota when many certificates are picked up concurrently.
+func (c *Connector) SetRetrievePollInterval(interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("%w: retrieve poll interval must be positive, got %s", verror.UserDataError, interval)
+	}
+	c.retrievePollInterval = interval
+	return nil
+}
+
+func (c *Connector) getPollInterval() time.Duration {
+	if c.retrievePollInterval > 0 {
+		return c.retrievePollInterval
+	}
+	return pollInterval
+}
+
+// SetImportEncoding sets how ImportCertificate uploads certificates
+func (c *Connector) SetImportEncoding(encoding ImportEncoding) {
+	c.importEncoding = encoding
 }
 
 func (c *Connector) SetHTTPClient(client *http.Client) {
-	c.client = client
+	c.state.lock()
+	defer c.state.unlock()
+	c.callerClient = client
+	c.applyTransportOptions()
+}
+
+// SetHTTPTimeout sets the timeout of the requests made by the default HTTP client, 30 seconds if not set.
+// It has no effect on a client set with SetHTTPClient.
+func (c *Connector) SetHTTPTimeout(timeout time.Duration) {
+	c.state.lock()
+	defer c.state.unlock()
+	c.httpTimeout = timeout
+	// the default client is made again with the new timeout
+	c.defaultClient = nil
 }
 
 func (c *Connector) ListCertificates(filter endpoint.Filter) ([]certificate.CertificateInfo, error) {
-	if c.zone.String() == "" {
-		return nil, fmt.Errorf("empty zone")
+	var infos []certificate.CertificateInfo
+	err := c.ListCertificatesFunc(filter, func(batch []certificate.CertificateInfo) error {
+		infos = append(infos, batch...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if infos == nil {
+		infos = []certificate.CertificateInfo{}
+	}
+	return infos, nil
+}
+
+// ListCertificatesFunc works like ListCertificates but calls fn with every page of certificates as it arrives
+// instead of collecting them, so the memory used doesn't grow with the inventory. Listing stops when fn returns
+// an error, which is then returned.
+func (c *Connector) ListCertificatesFunc(filter endpoint.Filter, fn func([]certificate.CertificateInfo) error) error {
+	if c.currentZone().String() == "" {
+		return fmt.Errorf("empty zone")
 	}
 	const batchSize = 50
 	limit := 100000000
 	if filter.Limit != nil {
 		limit = *filter.Limit
 	}
-	var buf [][]certificate.CertificateInfo
 	for page := 0; limit > 0; limit, page = limit-batchSize, page+1 {
-		var b []certificate.CertificateInfo
-		var err error
-		b, err = c.getCertsBatch(page, batchSize, filter.WithExpired)
+		b, err := c.getCertsBatch(page, batchSize, filter)
+		if err != nil {
+			return err
+		}
 		if limit < batchSize && len(b) > limit {
 			b = b[:limit]
 		}
-		if err != nil {
-			return nil, err
+		if len(b) > 0 {
+			if err = fn(b); err != nil {
+				return err
+			}
 		}
-		buf = append(buf, b)
 		if len(b) < batchSize {
 			break
 		}
 	}
-	sumLen := 0
-	for _, b := range buf {
-		sumLen += len(b)
+	return nil
+}
+
+func (c *Connector) getCertsBatch(page, pageSize int, filter endpoint.Filter) ([]certificate.CertificateInfo, error) {
+	certs, err := c.searchCertsBatch(page, pageSize, filter)
+	if err != nil {
+		return nil, err
 	}
-	infos := make([]certificate.CertificateInfo, sumLen)
-	offset := 0
-	for _, b := range buf {
-		copy(infos[offset:], b[:])
-		offset += len(b)
+	infos := make([]certificate.CertificateInfo, len(certs))
+	for i, c := range certs {
+		infos[i] = c.ToCertificateInfo()
 	}
 	return infos, nil
 }
 
-func (c *Connector) getCertsBatch(page, pageSize int, withExpired bool) ([]certificate.CertificateInfo, error) {
-
-	appDetails, err := c.getAppDetailsByName(c.zone.getApplicationName())
+func (c *Connector) searchCertsBatch(page, pageSize int, filter endpoint.Filter) ([]Certificate, error) {
+	appDetails, err := c.zoneAppDetails()
 	if err != nil {
 		return nil, err
 	}
@@ -708,28 +1277,74 @@ func (c *Connector) getCertsBatch(page, pageSize int, withExpired bool) ([]certi
 		},
 		Paging: &Paging{PageSize: pageSize, PageNumber: page},
 	}
-	if !withExpired {
+	if !filter.WithExpired {
 		req.Expression.Operands = append(req.Expression.Operands, Operand{
 			"validityEnd",
 			GTE,
-			time.Now().Format(time.RFC3339),
+			c.validityThreshold().Format(time.RFC3339),
 		})
 	}
+	req.Expression.Operands = append(req.Expression.Operands, validityWindowOperands(filter)...)
+	for _, f := range filter.CustomFields {
+		req.Expression.Operands = append(req.Expression.Operands, customFieldOperand(f))
+	}
 	r, err := c.searchCertificates(req)
 	if err != nil {
 		return nil, err
 	}
-	infos := make([]certificate.CertificateInfo, len(r.Certificates))
-	for i, c := range r.Certificates {
-		infos[i] = c.ToCertificateInfo()
+	return r.Certificates, nil
+}
+
+// ListCertificatesByLocation returns all certificates that were requested with the given instance (node name) in
+// their usage metadata, regardless of application. It's the inverse of setting certificate.Request.Location and
+// is useful to find what has to be cleaned up when a host is decommissioned.
+func (c *Connector) ListCertificatesByLocation(instance string) ([]certificate.CertificateInfo, error) {
+	if instance == "" {
+		return nil, fmt.Errorf("%w: instance name can not be empty", verror.UserDataError)
+	}
+	const batchSize = 50
+	var infos []certificate.CertificateInfo
+	for page := 0; ; page++ {
+		r, err := c.searchCertificates(locationSearchRequest(instance, page, batchSize))
+		if err != nil {
+			return nil, err
+		}
+		for _, cert := range r.Certificates {
+			infos = append(infos, cert.ToCertificateInfo())
+		}
+		if len(r.Certificates) < batchSize {
+			return infos, nil
+		}
+	}
+}
+
+func locationSearchRequest(instance string, page, pageSize int) *SearchRequest {
+	return &SearchRequest{
+		Expression: &Expression{
+			Operands: []Operand{
+				{"certificateUsageMetadata.nodeName", EQ, instance},
+			},
+		},
+		Paging: &Paging{PageSize: pageSize, PageNumber: page},
 	}
-	return infos, nil
 }
 
 func (c *Connector) getAppDetailsByName(appName string) (*ApplicationDetails, error) {
+	if details := c.cache.getAppDetails(appName, c.now()); details != nil {
+		return details, nil
+	}
+	details, err := c.fetchAppDetailsByName(appName)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.putAppDetails(appName, details, c.now())
+	return details, nil
+}
+
+func (c *Connector) fetchAppDetailsByName(appName string) (*ApplicationDetails, error) {
 	url := c.getURL(urlAppDetailsByName)
-	if c.user == nil {
-		return nil, fmt.Errorf("must be autheticated to read the zone configuration")
+	if err := c.requireAuthentication("read the zone configuration"); err != nil {
+		return nil, err
 	}
 	encodedAppName := netUrl.PathEscape(appName)
 	url = fmt.Sprintf(url, encodedAppName)
@@ -744,10 +1359,62 @@ func (c *Connector) getAppDetailsByName(appName string) (*ApplicationDetails, er
 	return details, nil
 }
 
+// GetApplicationByID returns the details of the application with the given ID, including its name.
+// verror.ApplicationNotFoundError is returned if there is no such application.
+func (c *Connector) GetApplicationByID(appID string) (*ApplicationDetails, error) {
+	if err := c.requireAuthentication("read applications"); err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf(c.getURL(urlAppDetailsByID), netUrl.PathEscape(appID))
+	statusCode, status, body, err := c.request("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	details, err := parseApplicationDetailsResult(statusCode, status, body)
+	if errors.Is(err, verror.ApplicationNotFoundError) {
+		return nil, fmt.Errorf("%w: %s", err, appID)
+	}
+	return details, err
+}
+
+// zoneAppDetails returns the details of the application of the connector zone
+func (c *Connector) zoneAppDetails() (*ApplicationDetails, error) {
+	zone := c.currentZone()
+	if err := zone.validate(); err != nil {
+		return nil, err
+	}
+	return c.getAppDetailsByName(zone.getApplicationName())
+}
+
 func (c *Connector) getTemplateByID() (*certificateTemplate, error) {
+	zone := c.currentZone()
+	if err := zone.validate(); err != nil {
+		return nil, err
+	}
+	if t := c.cache.getTemplate(zone.String(), c.now()); t != nil {
+		return t, nil
+	}
+	t, err := c.fetchTemplate(zone)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.putTemplate(zone.String(), t, c.now())
+	return t, nil
+}
+
+func (c *Connector) fetchTemplateByID(templateID string) (*certificateTemplate, error) {
+	url := c.getURL(urlIssuingTemplates) + "/" + netUrl.PathEscape(templateID)
+	statusCode, status, body, err := c.request("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return parseCertificateTemplateResult(statusCode, status, body)
+}
+
+func (c *Connector) fetchTemplate(zone cloudZone) (*certificateTemplate, error) {
 	url := c.getURL(urlResourceTemplate)
-	appNameEncoded := netUrl.PathEscape(c.zone.getApplicationName())
-	citAliasEncoded := netUrl.PathEscape(c.zone.getTemplateAlias())
+	appNameEncoded := netUrl.PathEscape(zone.getApplicationName())
+	citAliasEncoded := netUrl.PathEscape(zone.getTemplateAlias())
 	url = fmt.Sprintf(url, appNameEncoded, citAliasEncoded)
 	statusCode, status, body, err := c.request("GET", url, nil)
 	if err != nil {
@@ -20,16 +20,22 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha1"
+	"crypto/sha256"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/hex"
+	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -712,6 +718,20 @@ func TestSetBaseURL(t *testing.T) {
 	}
 }
 
+func TestOfflineNormalizeURLInvalid(t *testing.T) {
+	for _, url := range []string{"", "api.venafi.cloud", "https://127.0.0.1:8443/", "localhost:8443"} {
+		if _, err := normalizeURL(url); err != nil {
+			t.Fatalf("expected %q to be valid, err: %s", url, err)
+		}
+	}
+	for _, url := range []string{"htps://api.venafi.cloud", "https://", "https://api venafi.cloud", "api.venafi.cloud:port",
+		"https://api..venafi.cloud", "https://user@api.venafi.cloud", "https://api.venafi.cloud/?x=1"} {
+		if _, err := normalizeURL(url); !errors.Is(err, verror.UserDataError) {
+			t.Fatalf("expected user data error for %q, got %v", url, err)
+		}
+	}
+}
+
 func TestGetURL(t *testing.T) {
 	var err error
 	condor := Connector{}
@@ -803,3 +823,804 @@ func TestSearchCertificate(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestMockRequestCertificateWithParsedCSR(t *testing.T) {
+	var submitted certificateRequest
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + basePath + "applications/name/App":
+			_, _ = w.Write(successGetAppDetails)
+		case "/" + string(urlResourceCertificateRequests):
+			body, _ := ioutil.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &submitted)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write(successRequestCertificate)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+	csr := newTestCSR(t, "parsed.vfidev.com")
+	req := &certificate.Request{ParsedCSR: csr}
+	id, err := conn.RequestCertificate(req)
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if id != "04c051d0-f118-11e5-8b33-d96cf8021ce5" {
+		t.Fatalf("unexpected request id %s", id)
+	}
+	expected := string(pem.EncodeToMemory(certificate.GetCertificateRequestPEMBlock(csr.Raw)))
+	if submitted.CSR != expected {
+		t.Fatalf("submitted CSR is not the PEM-encoded parsed CSR\nget:    %s\nexpect: %s", submitted.CSR, expected)
+	}
+
+	csr.Signature[0] ^= 0xff
+	_, err = conn.RequestCertificate(&certificate.Request{ParsedCSR: csr})
+	if err == nil {
+		t.Fatalf("err nil, expected error back for CSR with bad signature")
+	}
+}
+
+func TestMockRequestCertificateExtraHeaders(t *testing.T) {
+	traceHeaders := make(map[string]string)
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		traceHeaders[r.Method+" "+r.URL.Path] = r.Header.Get("X-Trace-Id")
+		if r.Header.Get("tppl-api-key") != "mock-api-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/" + basePath + "applications/name/App":
+			_, _ = w.Write(successGetAppDetails)
+		case "/" + string(urlResourceCertificateRequests):
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write(successRequestCertificate)
+		case "/" + string(urlResourceUserAccounts):
+			_, _ = w.Write(successGetUserAccount)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	req := &certificate.Request{ParsedCSR: newTestCSR(t, "headers.vfidev.com")}
+	req.ExtraHeaders = map[string]string{"X-Trace-Id": "trace-1", "TPPL-API-KEY": "other"}
+	_, err := conn.RequestCertificate(req)
+	if !errors.Is(err, verror.UserDataError) {
+		t.Fatalf("expected UserDataError when overriding the api key header, got: %v", err)
+	}
+
+	req.ExtraHeaders = map[string]string{"X-Trace-Id": "trace-1"}
+	_, err = conn.RequestCertificate(req)
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if traceHeaders["POST /"+string(urlResourceCertificateRequests)] != "trace-1" {
+		t.Fatalf("expected trace header on the certificate request call, got %v", traceHeaders)
+	}
+	if traceHeaders["GET /"+basePath+"applications/name/App"] != "trace-1" {
+		t.Fatalf("expected trace header on the application lookup call, got %v", traceHeaders)
+	}
+
+	err = conn.Authenticate(&endpoint.Authentication{APIKey: "mock-api-key"})
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if h := traceHeaders["GET /"+string(urlResourceUserAccounts)]; h != "" {
+		t.Fatalf("expected no trace header on other calls, got %s", h)
+	}
+}
+
+func TestMockRequestCertificateOrigin(t *testing.T) {
+	var sent certificateRequest
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + basePath + "applications/name/App":
+			_, _ = w.Write(successGetAppDetails)
+		case "/" + string(urlResourceCertificateRequests):
+			_ = json.NewDecoder(r.Body).Decode(&sent)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write(successRequestCertificate)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	cases := []struct {
+		origin   string
+		fields   []certificate.CustomField
+		expected string
+	}{
+		{expected: endpoint.SDKName},
+		{fields: []certificate.CustomField{{Type: certificate.CustomFieldOrigin, Value: "legacy"}}, expected: "legacy"},
+		{origin: "integration", fields: []certificate.CustomField{{Type: certificate.CustomFieldOrigin, Value: "legacy"}}, expected: "integration"},
+	}
+	for _, c := range cases {
+		req := &certificate.Request{ParsedCSR: newTestCSR(t, "origin.vfidev.com"), Origin: c.origin, CustomFields: c.fields}
+		if _, err := conn.RequestCertificate(req); err != nil {
+			t.Fatalf("err is not nil, err: %s", err)
+		}
+		if sent.ApiClientInformation == nil || sent.ApiClientInformation.Type != c.expected {
+			t.Fatalf("expected origin %q, got %+v", c.expected, sent.ApiClientInformation)
+		}
+	}
+}
+
+func TestMockRequestCertificateClientIP(t *testing.T) {
+	var sent certificateRequest
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + basePath + "applications/name/App":
+			_, _ = w.Write(successGetAppDetails)
+		case "/" + string(urlResourceCertificateRequests):
+			_ = json.NewDecoder(r.Body).Decode(&sent)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write(successRequestCertificate)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	// the mock server listens on the loopback interface, so it's the address used to reach it
+	req := &certificate.Request{ParsedCSR: newTestCSR(t, "client.vfidev.com")}
+	if _, err := conn.RequestCertificate(req); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if sent.ApiClientInformation.Identifier != "127.0.0.1" {
+		t.Fatalf("expected the detected local address, got %s", sent.ApiClientInformation.Identifier)
+	}
+
+	req = &certificate.Request{ParsedCSR: newTestCSR(t, "client.vfidev.com"), ClientIP: "10.1.2.3"}
+	if _, err := conn.RequestCertificate(req); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if sent.ApiClientInformation.Identifier != "10.1.2.3" {
+		t.Fatalf("expected the client IP set by the caller, got %s", sent.ApiClientInformation.Identifier)
+	}
+
+	req = &certificate.Request{ParsedCSR: newTestCSR(t, "client.vfidev.com"), ClientIP: "host-1"}
+	if _, err := conn.RequestCertificate(req); !errors.Is(err, verror.UserDataError) {
+		t.Fatalf("expected user data error for an invalid client IP, got %v", err)
+	}
+}
+
+func TestMockSetDefaultHeaders(t *testing.T) {
+	received := make(map[string]http.Header)
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		received[r.Method+" "+r.URL.Path] = r.Header.Clone()
+		switch r.URL.Path {
+		case "/" + basePath + "applications/name/App":
+			_, _ = w.Write(successGetAppDetails)
+		case "/" + string(urlResourceCertificateRequests):
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write(successRequestCertificate)
+		case "/" + string(urlResourceUserAccounts):
+			_, _ = w.Write(successGetUserAccount)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	if err := conn.SetDefaultHeaders(map[string]string{"X-Correlation-Id": "c1", "tppl-api-key": "other"}); !errors.Is(err, verror.UserDataError) {
+		t.Fatalf("expected UserDataError when overriding the api key header, got: %v", err)
+	}
+	if err := conn.SetDefaultHeaders(map[string]string{"X-Correlation-Id": "c1", "X-Trace-Id": "default"}); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if err := conn.Authenticate(&endpoint.Authentication{APIKey: "mock-api-key"}); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	h := received["GET /"+string(urlResourceUserAccounts)]
+	if h.Get("X-Correlation-Id") != "c1" || h.Get("tppl-api-key") != "mock-api-key" {
+		t.Fatalf("expected default header alongside the api key, got %v", h)
+	}
+
+	req := &certificate.Request{ParsedCSR: newTestCSR(t, "headers.vfidev.com")}
+	req.ExtraHeaders = map[string]string{"X-Trace-Id": "trace-1"}
+	if _, err := conn.RequestCertificate(req); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	h = received["POST /"+string(urlResourceCertificateRequests)]
+	if h.Get("X-Correlation-Id") != "c1" || h.Get("X-Trace-Id") != "trace-1" {
+		t.Fatalf("expected default and request headers with the request one taking precedence, got %v", h)
+	}
+}
+
+func TestMockListCertificatesByLocation(t *testing.T) {
+	var searchBodies []string
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+string(urlResourceCertificateSearch) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		searchBodies = append(searchBodies, string(body))
+		_, _ = w.Write([]byte(`{"count":1,"certificates":[{"id":"c1","subjectCN":["node.vfidev.com"]}]}`))
+	})
+	defer server.Close()
+
+	infos, err := conn.ListCertificatesByLocation("node-01")
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if len(infos) != 1 || infos[0].ID != "c1" || infos[0].CN != "node.vfidev.com" {
+		t.Fatalf("unexpected certificates %+v", infos)
+	}
+	expected := `{"expression":{"operands":[{"field":"certificateUsageMetadata.nodeName","operator":"EQ","value":"node-01"}]},"paging":{"pageNumber":0,"pageSize":50}}`
+	if len(searchBodies) != 1 || searchBodies[0] != expected {
+		t.Fatalf("unexpected search requests\nget:    %v\nexpect: %s", searchBodies, expected)
+	}
+
+	if _, err = conn.ListCertificatesByLocation(""); !errors.Is(err, verror.UserDataError) {
+		t.Fatalf("expected user data error for empty instance, got %v", err)
+	}
+}
+
+func TestMockRequestCertificateWarnings(t *testing.T) {
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + basePath + "applications/name/App":
+			_, _ = w.Write(successGetAppDetails)
+		case "/" + string(urlResourceCertificateRequests):
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"certificateRequests":[{"id":"04c051d0-f118-11e5-8b33-d96cf8021ce5","status":"REQUESTED"}],` +
+				`"warnings":[{"code":10750,"message":"validity clamped to 90 days"},{"message":"deprecated template"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+	req := &certificate.Request{ParsedCSR: newTestCSR(t, "warnings.vfidev.com")}
+	if _, err := conn.RequestCertificate(req); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	expected := []string{"validity clamped to 90 days (code 10750)", "deprecated template"}
+	if !reflect.DeepEqual(req.Warnings, expected) {
+		t.Fatalf("unexpected warnings\nget:    %v\nexpect: %v", req.Warnings, expected)
+	}
+}
+
+func TestOfflineNotAuthenticated(t *testing.T) {
+	conn, err := NewConnector("", mockZone, false, nil)
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if _, err = conn.CompanyID(); !errors.Is(err, verror.AuthError) {
+		t.Fatalf("expected auth error from CompanyID, got %v", err)
+	}
+	checks := map[string]func() error{
+		"RequestCertificate": func() error {
+			_, err := conn.RequestCertificate(&certificate.Request{})
+			return err
+		},
+		"RetrieveCertificate": func() error {
+			_, err := conn.RetrieveCertificate(&certificate.Request{PickupID: "id"})
+			return err
+		},
+		"ReadZoneConfiguration": func() error {
+			_, err := conn.ReadZoneConfiguration()
+			return err
+		},
+		"ListCertificates": func() error {
+			_, err := conn.ListCertificates(endpoint.Filter{})
+			return err
+		},
+	}
+	for name, check := range checks {
+		err := check()
+		if !errors.Is(err, verror.AuthError) || !strings.Contains(err.Error(), "must be authenticated") {
+			t.Errorf("%s: expected a clear authentication error, got %v", name, err)
+		}
+	}
+}
+
+func TestMockDefaultChainOption(t *testing.T) {
+	var chainOrders []string
+	ca := newMockCA(t)
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/contents") {
+			chainOrders = append(chainOrders, r.URL.Query().Get("chainOrder"))
+		}
+		ca(w, r)
+	})
+	defer server.Close()
+	conn.SetDefaultChainOption(certificate.ChainOptionRootFirst)
+
+	retrieve := func(req *certificate.Request) {
+		if _, err := conn.RequestCertificate(req); err != nil {
+			t.Fatalf("err is not nil, err: %s", err)
+		}
+		// the mock CA reports each request pending once
+		if _, err := conn.RetrieveCertificate(req); !errors.As(err, &endpoint.ErrCertificatePending{}) {
+			t.Fatalf("expected pending certificate, got %v", err)
+		}
+		if _, err := conn.RetrieveCertificate(req); err != nil {
+			t.Fatalf("err is not nil, err: %s", err)
+		}
+	}
+	retrieve(&certificate.Request{ParsedCSR: newTestCSR(t, "default.vfidev.com")})
+	retrieve(&certificate.Request{ParsedCSR: newTestCSR(t, "ignore.vfidev.com"), ChainOption: certificate.ChainOptionIgnore})
+
+	expected := []string{string(ChainOrderRootFirst), string(ChainOrderEEFirst)}
+	if !reflect.DeepEqual(chainOrders, expected) {
+		t.Fatalf("unexpected chain orders\nget:    %v\nexpect: %v", chainOrders, expected)
+	}
+}
+
+func TestMockRetrieveCertificateExpectedThumbprint(t *testing.T) {
+	conn, server := newMockConnector(t, newMockCA(t))
+	defer server.Close()
+	req := &certificate.Request{ParsedCSR: newTestCSR(t, "pinned.vfidev.com")}
+	if _, err := conn.RequestCertificate(req); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	req.Timeout = 0
+	_, _ = conn.RetrieveCertificate(req) // the mock CA reports each request pending once
+	pcc, err := conn.RetrieveCertificate(req)
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	b, _ := pem.Decode([]byte(pcc.Certificate))
+	sum := sha256.Sum256(b.Bytes)
+	var pinned []string
+	for _, octet := range sum {
+		pinned = append(pinned, fmt.Sprintf("%02x", octet))
+	}
+
+	req.ExpectedThumbprint = strings.Join(pinned, ":")
+	if _, err = conn.RetrieveCertificate(req); err != nil {
+		t.Fatalf("expected matching thumbprint to pass, err: %s", err)
+	}
+
+	req.ExpectedThumbprint = strings.Repeat("AB", sha256.Size)
+	if _, err = conn.RetrieveCertificate(req); !errors.Is(err, verror.CertificateCheckError) {
+		t.Fatalf("expected certificate check error for mismatching thumbprint, got %v", err)
+	}
+}
+
+func TestMockGetRequestCSR(t *testing.T) {
+	csr := newTestCSR(t, "audit.vfidev.com", "audit.vfidev.com")
+	csrPEM := pem.EncodeToMemory(certificate.GetCertificateRequestPEMBlock(csr.Raw))
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + fmt.Sprintf(string(urlResourceCertificateStatus), "with-csr"):
+			b, _ := json.Marshal(certificateStatus{Id: "with-csr", Status: "ISSUED", CertificateSigningRequest: string(csrPEM)})
+			_, _ = w.Write(b)
+		case "/" + fmt.Sprintf(string(urlResourceCertificateStatus), "without-csr"):
+			_, _ = w.Write([]byte(`{"id":"without-csr","status":"ISSUED"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	b, err := conn.GetRequestCSR("with-csr")
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	p, _ := pem.Decode(b)
+	parsed, err := x509.ParseCertificateRequest(p.Bytes)
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if parsed.Subject.CommonName != "audit.vfidev.com" || !reflect.DeepEqual(parsed.DNSNames, []string{"audit.vfidev.com"}) {
+		t.Fatalf("unexpected CSR %s %v", parsed.Subject.CommonName, parsed.DNSNames)
+	}
+
+	if _, err = conn.GetRequestCSR("without-csr"); err == nil || !strings.Contains(err.Error(), "no stored CSR") {
+		t.Fatalf("expected error for request without CSR, got %v", err)
+	}
+}
+
+func TestMockRetrieveCertificateTimeoutFakeClock(t *testing.T) {
+	defer func(interval time.Duration) { pollInterval = interval }(pollInterval)
+	pollInterval = time.Millisecond
+	// every poll moves the fake clock a minute forward, reading the clock doesn't
+	var mu sync.Mutex
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	polls := 0
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		polls++
+		now = now.Add(time.Minute)
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"id":"pending","status":"PENDING"}`))
+	})
+	defer server.Close()
+	conn.nowFunc = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+
+	start := time.Now()
+	_, err := conn.RetrieveCertificate(&certificate.Request{PickupID: "pending", Timeout: 5 * time.Minute})
+	if !errors.As(err, &endpoint.ErrRetrieveCertificateTimeout{}) {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	// the 5 minutes timeout is passed only after the sixth poll
+	if polls != 6 {
+		t.Fatalf("expected timeout after 6 polls, got %d", polls)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("timeout should be driven by the fake clock, took %v", time.Since(start))
+	}
+}
+
+func TestMockGetApplicationByID(t *testing.T) {
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + basePath + "applications/a1b2c3d4-0000-11eb-0000-000000000000":
+			_, _ = w.Write([]byte(`{"id":"a1b2c3d4-0000-11eb-0000-000000000000","name":"App"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[{"code":10051,"message":"Unable to find application"}]}`))
+		}
+	})
+	defer server.Close()
+
+	app, err := conn.GetApplicationByID("a1b2c3d4-0000-11eb-0000-000000000000")
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if app.Name != "App" || app.ApplicationId != "a1b2c3d4-0000-11eb-0000-000000000000" {
+		t.Fatalf("unexpected application %+v", app)
+	}
+
+	_, err = conn.GetApplicationByID("unknown")
+	if !errors.Is(err, verror.ApplicationNotFoundError) {
+		t.Fatalf("expected application not found error, got: %v", err)
+	}
+}
+
+func TestMockRequestCertificateMinimalBody(t *testing.T) {
+	b, err := json.Marshal(certificateRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "{}" {
+		t.Fatalf("expected unset fields to be omitted, got %s", b)
+	}
+
+	var body map[string]interface{}
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + basePath + "applications/name/App":
+			_, _ = w.Write(successGetAppDetails)
+		case "/" + string(urlResourceCertificateRequests):
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Error(err)
+			}
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"certificateRequests":[{"id":"r1","status":"REQUESTED"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	req := &certificate.Request{CsrOrigin: certificate.UserProvidedCSR}
+	if err := req.SetCSR(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: newTestCSR(t, "minimal.vfidev.com").Raw})); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.RequestCertificate(req); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	expected := []string{"apiClientInformation", "applicationId", "certificateIssuingTemplateId", "certificateSigningRequest"}
+	if len(body) != len(expected) {
+		t.Fatalf("expected only %v to be sent, got %v", expected, body)
+	}
+	for _, k := range expected {
+		if v, ok := body[k]; !ok || v == "" {
+			t.Fatalf("expected %s to be sent, got %v", k, body)
+		}
+	}
+}
+
+func TestMockRetrieveIfValid(t *testing.T) {
+	conn, server := newMockConnector(t, newMockCA(t))
+	defer server.Close()
+	req := &certificate.Request{ParsedCSR: newTestCSR(t, "reuse.vfidev.com")}
+	if _, err := conn.RequestCertificate(req); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	_, _ = conn.RetrieveCertificate(req) // the mock CA reports each request pending once
+
+	// the mock CA issues certificates valid for an hour
+	if _, err := conn.RetrieveIfValid(req, 59*time.Minute); err != nil {
+		t.Fatalf("expected certificate to be reusable, err: %s", err)
+	}
+	if _, err := conn.RetrieveIfValid(req, 61*time.Minute); !errors.Is(err, verror.CertificateRenewalNeededError) {
+		t.Fatalf("expected renewal needed error, got %v", err)
+	}
+}
+
+func TestMockRetrieveCertificateReturnPendingOnTimeout(t *testing.T) {
+	defer func(interval time.Duration) { pollInterval = interval }(pollInterval)
+	pollInterval = time.Millisecond
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"pending","status":"PENDING"}`))
+	})
+	defer server.Close()
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	conn.nowFunc = func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	}
+
+	req := &certificate.Request{PickupID: "pending", Timeout: 5 * time.Minute, ReturnPendingOnTimeout: true}
+	_, err := conn.RetrieveCertificate(req)
+	var pending endpoint.ErrCertificatePending
+	if !errors.As(err, &pending) {
+		t.Fatalf("expected pending error, got %v", err)
+	}
+	if pending.CertificateID != "pending" || pending.Status != "PENDING" {
+		t.Fatalf("unexpected pending result %+v", pending)
+	}
+}
+
+func TestOfflineNewConnectorZone(t *testing.T) {
+	conn, err := NewConnector("", "  App\\Template \n", false, nil)
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if conn.zone.String() != "App\\Template" || conn.zone.getApplicationName() != "App" || conn.zone.getTemplateAlias() != "Template" {
+		t.Fatalf("zone was not normalized: %q", conn.zone.String())
+	}
+
+	conn, err = NewConnector("", "", false, nil)
+	if err != nil {
+		t.Fatalf("empty zone should be allowed, err: %s", err)
+	}
+	conn.SetZone(mockZone)
+	if conn.zone.getApplicationName() != "App" {
+		t.Fatalf("zone set later was not applied: %q", conn.zone.String())
+	}
+
+	for _, zone := range []string{"App", "App\\Template\\Extra", "\\Template", "App\\ "} {
+		if _, err = NewConnector("", zone, false, nil); !errors.Is(err, verror.UserDataError) {
+			t.Fatalf("expected user data error for zone %q, got %v", zone, err)
+		}
+	}
+}
+
+func TestMockFailoverURLs(t *testing.T) {
+	var primaryCalls int
+	conn, primary := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		primaryCalls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	defer primary.Close()
+	secondary := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(headerNameAPIKey) != "mock-api-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write(successGetAppDetails)
+	}))
+	defer secondary.Close()
+
+	if err := conn.SetFailoverURLs([]string{secondary.URL}); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	details, err := conn.fetchAppDetailsByName("App")
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if details.ApplicationId == "" || primaryCalls != 1 {
+		t.Fatalf("expected the secondary to answer after the primary failed, primary calls: %d", primaryCalls)
+	}
+}
+
+func TestMockFailoverURLsPOST(t *testing.T) {
+	var primaryCalls, secondaryCalls int
+	conn, primary := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		primaryCalls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	defer primary.Close()
+	secondary := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secondaryCalls++
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer secondary.Close()
+
+	if err := conn.SetFailoverURLs([]string{secondary.URL}); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	statusCode, _, _, err := conn.request("POST", conn.getURL(urlResourceCertificateRequests), certificateRequest{})
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if statusCode != http.StatusServiceUnavailable || primaryCalls != 1 || secondaryCalls != 0 {
+		t.Fatalf("expected the POST received by the primary not to be sent to the secondary, got %d, primary calls: %d, secondary calls: %d",
+			statusCode, primaryCalls, secondaryCalls)
+	}
+
+	// a POST which didn't reach the server is sent to the secondary
+	primary.Close()
+	if statusCode, _, _, err = conn.request("POST", conn.getURL(urlResourceCertificateRequests), certificateRequest{}); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if statusCode != http.StatusCreated || secondaryCalls != 1 {
+		t.Fatalf("expected the secondary to answer when the primary is down, got %d, secondary calls: %d", statusCode, secondaryCalls)
+	}
+}
+
+func TestMockGetCertificateInstallations(t *testing.T) {
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+string(urlResourceCertificates)+"/c1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"c1","certificateUsageMetadata":[` +
+			`{"appName":"nginx","nodeName":"web-1"},{"appName":"haproxy","nodeName":"lb-1","automationMetadata":"ansible"}]}`))
+	})
+	defer server.Close()
+
+	locations, err := conn.GetCertificateInstallations("c1")
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	expected := []certificate.Location{{Instance: "web-1", Workload: "nginx"}, {Instance: "lb-1", Workload: "haproxy"}}
+	if !reflect.DeepEqual(locations, expected) {
+		t.Fatalf("unexpected installations\nget:    %v\nexpect: %v", locations, expected)
+	}
+}
+
+func TestMockEmptyResponseBody(t *testing.T) {
+	csrPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: newTestCSR(t, "empty.vfidev.com").Raw})
+	var created string
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + basePath + "applications/name/App":
+			_, _ = w.Write(successGetAppDetails)
+		case "/" + string(urlResourceCertificateRequests) + "/r1":
+			status, _ := json.Marshal(certificateStatus{Id: "r1", Status: "ISSUED", CertificateIdsList: []string{"c1"},
+				ApplicationId: "a1", TemplateId: "t1", CertificateSigningRequest: string(csrPEM)})
+			_, _ = w.Write(status)
+		case "/" + string(urlResourceCertificates) + "/c1":
+			_, _ = w.Write([]byte(`{"id":"c1","certificateRequestId":"r1"}`))
+		case "/" + string(urlIssuingTemplates) + "/t1":
+			_, _ = w.Write([]byte(`{"id":"t1","name":"Template","keyReuse":true}`))
+		case "/" + string(urlResourceCertificateRequests):
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(created))
+		case "/" + string(urlResourceCertificates):
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	// responses which must contain the created requests fail instead of panicking
+	for _, body := range []string{"", " \n", `{}`, `{"certificateRequests":[]}`} {
+		created = body
+		req := &certificate.Request{CsrOrigin: certificate.UserProvidedCSR}
+		if err := req.SetCSR(csrPEM); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conn.RequestCertificate(req); !errors.Is(err, verror.ServerError) && !errors.Is(err, verror.ServerBadDataResponce) {
+			t.Fatalf("expected server error for the created response %q, got %v", body, err)
+		}
+		if _, err := conn.RenewCertificate(&certificate.RenewalRequest{CertificateDN: "r1", CertificateRequest: &certificate.Request{}}); err == nil {
+			t.Fatalf("expected renewal to fail for the created response %q", body)
+		}
+	}
+	if _, err := parseApplicationDetailsResult(http.StatusOK, "", []byte(" \n")); err == nil {
+		t.Fatalf("expected error for an empty application details response")
+	}
+
+	// no content is expected from some endpoints
+	if _, err := conn.postImportRequest(importRequest{}); err != nil {
+		t.Fatalf("no content status should not fail, err: %s", err)
+	}
+}
+
+func TestOfflineChainOrderFor(t *testing.T) {
+	cases := map[certificate.ChainOption]ChainOrder{
+		certificate.ChainOptionRootLast:  ChainOrderEEFirst,
+		certificate.ChainOptionRootFirst: ChainOrderRootFirst,
+		certificate.ChainOptionIgnore:    ChainOrderEEFirst,
+		certificate.ChainOptionRootOnly:  ChainOrderEEFirst,
+	}
+	for option, expected := range cases {
+		if order := ChainOrderFor(option); order != expected {
+			t.Fatalf("chain option %d: expected %s, got %s", option, expected, order)
+		}
+	}
+}
+
+func TestMockSetRetrievePollInterval(t *testing.T) {
+	var polls []time.Time
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		polls = append(polls, time.Now())
+		_, _ = w.Write([]byte(`{"id":"pending","status":"PENDING"}`))
+	})
+	defer server.Close()
+
+	if err := conn.SetRetrievePollInterval(0); !errors.Is(err, verror.UserDataError) {
+		t.Fatalf("expected user data error for a zero interval, got %v", err)
+	}
+	if conn.getPollInterval() != pollInterval {
+		t.Fatalf("expected the default poll interval, got %s", conn.getPollInterval())
+	}
+	if err := conn.SetRetrievePollInterval(50 * time.Millisecond); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	_, err := conn.RetrieveCertificate(&certificate.Request{PickupID: "pending", Timeout: 120 * time.Millisecond})
+	if !errors.As(err, &endpoint.ErrRetrieveCertificateTimeout{}) {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	if len(polls) < 2 || len(polls) > 4 {
+		t.Fatalf("expected polls every 50ms, got %d polls", len(polls))
+	}
+	if d := polls[1].Sub(polls[0]); d < 50*time.Millisecond {
+		t.Fatalf("expected polls at least 50ms apart, got %s", d)
+	}
+}
+
+func TestMockRenewCertificateReuseCSR(t *testing.T) {
+	csr := newTestCSR(t, "reuse.vfidev.com")
+	csrPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr.Raw})
+	keyReuse := true
+	var sent certificateRequest
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + string(urlResourceCertificateRequests) + "/r1":
+			status, _ := json.Marshal(certificateStatus{Id: "r1", Status: "ISSUED", CertificateIdsList: []string{"c1"},
+				ApplicationId: "a1", TemplateId: "t1", CertificateSigningRequest: string(csrPEM)})
+			_, _ = w.Write(status)
+		case "/" + string(urlResourceCertificates) + "/c1":
+			_, _ = w.Write([]byte(`{"id":"c1","certificateRequestId":"r1"}`))
+		case "/" + string(urlIssuingTemplates) + "/t1":
+			_, _ = fmt.Fprintf(w, `{"id":"t1","name":"Template","keyReuse":%t}`, keyReuse)
+		case "/" + string(urlResourceCertificateRequests):
+			_ = json.NewDecoder(r.Body).Decode(&sent)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"certificateRequests":[{"id":"r2"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	renewReq := &certificate.RenewalRequest{CertificateDN: "r1", CertificateRequest: &certificate.Request{}}
+	requestID, err := conn.RenewCertificate(renewReq)
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if requestID != "r2" || !sent.ReuseCSR || sent.CSR != "" || sent.ExistingCertificateId != "c1" {
+		t.Fatalf("expected renewal reusing the CSR, got %s %+v", requestID, sent)
+	}
+
+	// the renewed certificate must be bound to the original key
+	caKey, err := certificate.GenerateECDSAPrivateKey(certificate.EllipticCurveP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: csr.Subject, NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour)}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, csr.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = renewReq.CertificateRequest.CheckCertificate(string(pem.EncodeToMemory(certificate.GetCertificatePEMBlock(der)))); err != nil {
+		t.Fatalf("expected the certificate with the original key to pass, got %s", err)
+	}
+	other, err := newSelfSignedCert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = renewReq.CertificateRequest.CheckCertificate(other); !errors.Is(err, verror.CertificateCheckError) {
+		t.Fatalf("expected certificate check error for another key, got %v", err)
+	}
+
+	keyReuse = false
+	if _, err = conn.RenewCertificate(&certificate.RenewalRequest{CertificateDN: "r1"}); !errors.Is(err, verror.UserDataError) {
+		t.Fatalf("expected user data error for a template disallowing key reuse, got %v", err)
+	}
+}
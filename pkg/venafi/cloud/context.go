@@ -0,0 +1,51 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"context"
+	"time"
+)
+
+// WithContext returns a copy of the connector whose API calls and waits for certificates are bound by ctx,
+// so a single deadline can cover all the round trips of an operation like RequestCertificate or RenewCertificate.
+// When ctx is done, the methods of the copy return ctx.Err().
+func (c *Connector) WithContext(ctx context.Context) *Connector {
+	// the HTTP client is created lazily, create it before copying so the copies share it
+	c.getHTTPClient()
+	clone := c.snapshot()
+	clone.ctx = ctx
+	return &clone
+}
+
+func (c *Connector) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// sleep waits for d unless the context of the connector is done first
+func (c *Connector) sleep(d time.Duration) error {
+	ctx := c.context()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
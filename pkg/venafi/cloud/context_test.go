@@ -0,0 +1,73 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+)
+
+func TestMockWithContext(t *testing.T) {
+	var slowCalls int32
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + string(urlResourceCertificateRequests) + "/pending":
+			_, _ = w.Write([]byte(`{"id":"pending","status":"PENDING"}`))
+		case "/" + string(urlResourceCertificateRequests) + "/slow":
+			atomic.AddInt32(&slowCalls, 1)
+			time.Sleep(200 * time.Millisecond)
+			_, _ = w.Write([]byte(`{"id":"slow","status":"PENDING"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+	if err := conn.SetRetrievePollInterval(10 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	// the deadline bounds waiting for the certificate even with a longer request timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := conn.WithContext(ctx).RetrieveCertificate(&certificate.Request{PickupID: "pending", Timeout: time.Minute})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("retrieval didn't stop at the deadline, took %s", time.Since(start))
+	}
+
+	// a request in flight is aborted and not retried
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = conn.WithContext(ctx).RetrieveCertificate(&certificate.Request{PickupID: "slow"})
+	if !errors.Is(err, context.DeadlineExceeded) || atomic.LoadInt32(&slowCalls) != 1 {
+		t.Fatalf("expected deadline exceeded without retries, got %v after %d calls", err, atomic.LoadInt32(&slowCalls))
+	}
+
+	// the original connector isn't bound by the context
+	if _, err = conn.getCertificateStatus("pending"); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+}
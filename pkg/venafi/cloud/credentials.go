@@ -0,0 +1,85 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+// tokenRefreshMargin is how long before its expiry a cached token is requested again
+const tokenRefreshMargin = 30 * time.Second
+
+// CredentialProvider supplies access tokens that may rotate, e.g. short-lived tokens exchanged for a workload identity
+type CredentialProvider interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// credentialCache keeps the last token of the provider until it's near its expiry.
+// The expiry is read from the "exp" claim when the token is a JWT, other tokens are not cached.
+type credentialCache struct {
+	mu       sync.Mutex
+	provider CredentialProvider
+	token    string
+	expires  time.Time
+}
+
+// SetCredentialProvider makes the connector send a token of the provider as a bearer token with every request
+// instead of the API key. Authenticate still has to be called to read the user details.
+func (c *Connector) SetCredentialProvider(provider CredentialProvider) {
+	c.credentials = &credentialCache{provider: provider}
+}
+
+func (cc *credentialCache) get(ctx context.Context, now time.Time) (string, error) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.token != "" && now.Add(tokenRefreshMargin).Before(cc.expires) {
+		return cc.token, nil
+	}
+	token, err := cc.provider.Token(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%w: failed to get token from credential provider: %v", verror.AuthError, err)
+	}
+	cc.token, cc.expires = token, jwtExpiry(token)
+	return token, nil
+}
+
+// jwtExpiry returns the expiry of a JWT or zero time if the token is not a JWT with the "exp" claim
+func jwtExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err = json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
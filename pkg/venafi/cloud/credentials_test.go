@@ -0,0 +1,87 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type rotatingProvider struct {
+	calls  int
+	tokens []string
+}
+
+func (p *rotatingProvider) Token(_ context.Context) (string, error) {
+	token := p.tokens[p.calls%len(p.tokens)]
+	p.calls++
+	return token, nil
+}
+
+func TestMockCredentialProvider(t *testing.T) {
+	var seen []string
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get("Authorization"))
+		if r.Header.Get(headerNameAPIKey) != "" {
+			t.Errorf("API key must not be sent with a credential provider")
+		}
+		_, _ = w.Write(successGetAppDetails)
+	})
+	defer server.Close()
+
+	provider := &rotatingProvider{tokens: []string{"token-1", "token-2"}}
+	conn.SetCredentialProvider(provider)
+	for i := 0; i < 3; i++ {
+		if _, err := conn.fetchAppDetailsByName("App"); err != nil {
+			t.Fatalf("err is not nil, err: %s", err)
+		}
+	}
+	expected := []string{"Bearer token-1", "Bearer token-2", "Bearer token-1"}
+	if !reflect.DeepEqual(seen, expected) {
+		t.Fatalf("unexpected authorization headers\nget:    %v\nexpect: %v", seen, expected)
+	}
+
+	// JWTs are cached until near their expiry
+	jwt := func(exp time.Time) string {
+		payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, exp.Unix())))
+		return "e30." + payload + ".sig"
+	}
+	now := time.Now()
+	conn.nowFunc = func() time.Time { return now }
+	provider = &rotatingProvider{tokens: []string{jwt(now.Add(time.Hour)), jwt(now.Add(2 * time.Hour))}}
+	conn.SetCredentialProvider(provider)
+	for i := 0; i < 2; i++ {
+		if _, err := conn.fetchAppDetailsByName("App"); err != nil {
+			t.Fatalf("err is not nil, err: %s", err)
+		}
+	}
+	if provider.calls != 1 {
+		t.Fatalf("expected the token to be cached, provider was called %d times", provider.calls)
+	}
+	now = now.Add(time.Hour - tokenRefreshMargin/2)
+	if _, err := conn.fetchAppDetailsByName("App"); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if provider.calls != 2 || seen[len(seen)-1] != "Bearer "+provider.tokens[1] {
+		t.Fatalf("expected a fresh token near expiry, provider was called %d times", provider.calls)
+	}
+}
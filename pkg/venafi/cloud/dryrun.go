@@ -0,0 +1,59 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+// DryRunOptions control the body returned by BuildRequest
+type DryRunOptions struct {
+	// Indent formats the JSON with indentation for human inspection
+	Indent bool
+	// MaxBodySize truncates the body to the number of bytes when positive, so the body is valid JSON
+	// only when it fits
+	MaxBodySize int
+}
+
+// BuildRequest returns the JSON body RequestCertificate would send for the request, without sending it.
+// The zone is read from the server to fill the application and template IDs.
+func (c *Connector) BuildRequest(req *certificate.Request, options DryRunOptions) ([]byte, error) {
+	if err := c.requireAuthentication("build a certificate request"); err != nil {
+		return nil, err
+	}
+	cloudReq, err := c.newCertificateRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	var body []byte
+	if options.Indent {
+		body, err = json.MarshalIndent(cloudReq, "", "  ")
+	} else {
+		body, err = json.Marshal(cloudReq)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to marshal certificate request: %v", verror.VcertError, err)
+	}
+	if options.MaxBodySize > 0 && len(body) > options.MaxBodySize {
+		body = body[:options.MaxBodySize]
+	}
+	return body, nil
+}
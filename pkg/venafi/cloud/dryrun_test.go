@@ -0,0 +1,80 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+)
+
+func TestMockBuildRequest(t *testing.T) {
+	var sent []byte
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + basePath + "applications/name/App":
+			_, _ = w.Write(successGetAppDetails)
+		case "/" + string(urlResourceCertificateRequests):
+			sent, _ = ioutil.ReadAll(r.Body)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"certificateRequests":[{"id":"r1"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+	req := &certificate.Request{ParsedCSR: newTestCSR(t, "dryrun.vfidev.com"), ValidityHours: 24,
+		Location: &certificate.Location{Instance: "node", Workload: "web"}}
+
+	built, err := conn.BuildRequest(req, DryRunOptions{Indent: true})
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if !json.Valid(built) || !bytes.Contains(built, []byte("\n  \"certificateSigningRequest\": ")) {
+		t.Fatalf("expected indented JSON, got:\n%s", built)
+	}
+	if sent != nil {
+		t.Fatal("BuildRequest must not send the request")
+	}
+	if _, err = conn.RequestCertificate(req); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	var dryRun, real map[string]interface{}
+	if err = json.Unmarshal(built, &dryRun); err != nil {
+		t.Fatal(err)
+	}
+	if err = json.Unmarshal(sent, &real); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dryRun, real) {
+		t.Fatalf("dry run differs from the sent request\nget:    %v\nexpect: %v", dryRun, real)
+	}
+
+	truncated, err := conn.BuildRequest(req, DryRunOptions{MaxBodySize: 20})
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if len(truncated) != 20 || !strings.HasPrefix(string(sent), string(truncated)) {
+		t.Fatalf("expected the body truncated to 20 bytes, got %q", truncated)
+	}
+}
@@ -17,8 +17,14 @@
 package cloud
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
 	"github.com/Venafi/vcert/v4/pkg/verror"
 )
 
@@ -32,12 +38,179 @@ type jsonData struct {
 	Errors []responseError `json:"errors,omitempty"`
 }
 
+// maxErrorBodyLength limits how much of an unexpected response body is included in an error
+const maxErrorBodyLength = 256
+
+var secretInBodyRegexp = regexp.MustCompile(`(?i)((?:api[-_]?key|token|password)["']?\s*[:=]\s*["']?)[^\s"',;&<]+`)
+
 func parseResponseErrors(b []byte) ([]responseError, error) {
 	var data jsonData
-	err := json.Unmarshal(b, &data)
+	err := unmarshalJSON(b, &data)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", verror.ServerError, err)
+		return nil, fmt.Errorf("%w: %v, response: %s", verror.ServerError, err, bodySnippet(b))
+	}
+	if len(data.Errors) == 0 {
+		return nil, fmt.Errorf("%w: unexpected error response format: %s", verror.ServerError, bodySnippet(b))
 	}
 
 	return data.Errors, nil
 }
+
+// mapStatusToError converts an unexpected response status to an error wrapping the matching verror sentinel,
+// so all the methods report the same error kinds. Error messages from the body are included when they can be parsed.
+func mapStatusToError(statusCode int, body []byte) error {
+	var kind error
+	switch {
+	case statusCode == http.StatusBadRequest:
+		kind = verror.ServerBadDataResponce
+	case statusCode == http.StatusUnauthorized, statusCode == http.StatusForbidden:
+		kind = verror.AuthError
+	case statusCode == http.StatusConflict:
+		kind = verror.ServerConflictError
+	case statusCode >= http.StatusInternalServerError:
+		kind = verror.ServerTemporaryUnavailableError
+	default:
+		kind = verror.ServerError
+	}
+	respErrors, err := parseResponseErrors(body)
+	if err != nil {
+		return fmt.Errorf("%w: unexpected status code %d, response: %s", kind, statusCode, bodySnippet(body))
+	}
+	apiErr := &CloudAPIError{StatusCode: statusCode, kind: kind}
+	for _, e := range respErrors {
+		apiErr.Errors = append(apiErr.Errors, CloudAPIErrorDetail{Code: e.Code, Message: e.Message, Args: errorArgs(e.Args)})
+	}
+	return apiErr
+}
+
+// CloudAPIErrorDetail is a single error of a Venafi Cloud error response
+type CloudAPIErrorDetail struct {
+	Code    int
+	Message string
+	// Args are the arguments of the error, like the names and values of the invalid fields of a request
+	Args []string
+}
+
+// errorArgs formats the arguments of an error response, a single value or a list of values
+func errorArgs(args interface{}) []string {
+	switch v := args.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		formatted := make([]string, 0, len(v))
+		for _, a := range v {
+			formatted = append(formatted, fmt.Sprint(a))
+		}
+		return formatted
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
+
+// CloudAPIError is returned for unexpected responses carrying Venafi Cloud errors, so callers can
+// branch on the error codes with errors.As. It wraps the verror sentinel of the status, see mapStatusToError.
+type CloudAPIError struct {
+	StatusCode int
+	Errors     []CloudAPIErrorDetail
+	kind       error
+}
+
+func (e *CloudAPIError) Error() string {
+	msg := fmt.Sprintf("unexpected status code %d\n", e.StatusCode)
+	for _, d := range e.Errors {
+		msg += fmt.Sprintf("Error Code: %d Error: %s", d.Code, d.Message)
+		if len(d.Args) > 0 {
+			msg += fmt.Sprintf(" (%s)", strings.Join(d.Args, ", "))
+		}
+		msg += "\n"
+	}
+	if e.kind == nil {
+		return msg
+	}
+	return e.kind.Error() + ": " + msg
+}
+
+func (e *CloudAPIError) Unwrap() error {
+	return e.kind
+}
+
+// HasCode reports whether the response contains the Venafi Cloud error code
+func (e *CloudAPIError) HasCode(code int) bool {
+	for _, d := range e.Errors {
+		if d.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// hasErrorCode reports whether the error response body contains the error code
+func hasErrorCode(body []byte, code int) bool {
+	respErrors, _ := parseResponseErrors(body)
+	for _, e := range respErrors {
+		if e.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// warningMessages formats warnings returned by the server along with a successful response
+func warningMessages(warnings []responseError) []string {
+	var messages []string
+	for _, w := range warnings {
+		if w.Code != 0 {
+			messages = append(messages, fmt.Sprintf("%s (code %d)", w.Message, w.Code))
+		} else {
+			messages = append(messages, w.Message)
+		}
+	}
+	return messages
+}
+
+// unmarshalJSON works like json.Unmarshal but keeps numbers decoded into interface{} values (like error args)
+// as json.Number, so large IDs and counts don't lose precision by conversion to float64.
+// An empty body is an error, callers expecting no content check for http.StatusNoContent first.
+func unmarshalJSON(b []byte, v interface{}) error {
+	if len(bytes.TrimSpace(b)) == 0 {
+		return fmt.Errorf("empty response body")
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(v); err != nil {
+		return err
+	}
+	if d.More() {
+		return fmt.Errorf("invalid character after top-level value")
+	}
+	return nil
+}
+
+// redacted replaces the credentials in the errors and logs
+const redacted = "[REDACTED]"
+
+// redactSecret masks the occurrences of the secret in s
+func redactSecret(s string, secret string) string {
+	if secret == "" {
+		return s
+	}
+	return strings.ReplaceAll(s, secret, redacted)
+}
+
+// bodySnippet returns a single-line, truncated and redacted representation of the raw response body
+func bodySnippet(b []byte) string {
+	s := strings.Join(strings.Fields(string(b)), " ")
+	s = secretInBodyRegexp.ReplaceAllString(s, "${1}***")
+	if s == "" {
+		return "<empty>"
+	}
+	if len(s) > maxErrorBodyLength {
+		// don't split a multi-byte character
+		end := maxErrorBodyLength
+		for end > 0 && !utf8.RuneStart(s[end]) {
+			end--
+		}
+		s = s[:end] + "...(truncated)"
+	}
+	return s
+}
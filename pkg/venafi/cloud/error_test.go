@@ -17,7 +17,15 @@
 package cloud
 
 import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
 	"testing"
+	"unicode/utf8"
+
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+	"github.com/Venafi/vcert/v4/pkg/verror"
 )
 
 func TestParseResponseErrors(t *testing.T) {
@@ -46,3 +54,143 @@ func TestParseResponseErrorWithArgs(t *testing.T) {
 		t.Fatalf("ParseResponseErrors returned incorrect code.  Expected: 10726 Actual: %d", errors[0].Code)
 	}
 }
+
+func TestParseResponseErrorsUnexpectedBody(t *testing.T) {
+	data := []byte("<html>\n<head><title>502 Bad Gateway</title></head>\n<body><h1>Bad Gateway</h1><p>api-key=cec682ba-f409-40c0-9b00-aeb67876b7a1</p>" + strings.Repeat("x", 500) + "</body></html>")
+	_, err := parseResponseErrors(data)
+	if err == nil {
+		t.Fatal("err nil, expected error back")
+	}
+	if !errors.Is(err, verror.ServerError) {
+		t.Fatalf("expected ServerError, got: %s", err)
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, "<title>502 Bad Gateway</title>") {
+		t.Fatalf("expected raw body in the error message, got: %s", msg)
+	}
+	if strings.Contains(msg, "cec682ba-f409-40c0-9b00-aeb67876b7a1") {
+		t.Fatalf("expected api key to be redacted, got: %s", msg)
+	}
+	if !strings.Contains(msg, "(truncated)") || strings.Contains(msg, strings.Repeat("x", 300)) {
+		t.Fatalf("expected body to be truncated, got: %s", msg)
+	}
+
+	_, err = parseResponseErrors([]byte(`{"error":"unauthorized"}`))
+	if err == nil || !strings.Contains(err.Error(), "unauthorized") {
+		t.Fatalf("expected error with the raw body for an unknown JSON shape, got: %v", err)
+	}
+}
+
+func TestParseBodySnippetMultiByte(t *testing.T) {
+	// the limit falls in the middle of a three byte character
+	snippet := bodySnippet([]byte("x" + strings.Repeat("€", maxErrorBodyLength)))
+	if !utf8.ValidString(snippet) {
+		t.Fatalf("expected the truncated body to be valid UTF-8, got %q", snippet)
+	}
+	if !strings.HasSuffix(snippet, "€...(truncated)") {
+		t.Fatalf("expected the body to be cut before the split character, got %q", snippet)
+	}
+}
+
+func TestParseResponseErrorsLargeNumberArgs(t *testing.T) {
+	// 2^53 + 1 can't be represented as float64
+	data := []byte(`{"errors":[{"code":10501,"message":"Quota exceeded","args":[{"count":9007199254740993}]}]}`)
+	respErrors, err := parseResponseErrors(data)
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	args, ok := respErrors[0].Args.([]interface{})
+	if !ok || len(args) != 1 {
+		t.Fatalf("unexpected args %#v", respErrors[0].Args)
+	}
+	count := args[0].(map[string]interface{})["count"]
+	if n, ok := count.(json.Number); !ok || n.String() != "9007199254740993" {
+		t.Fatalf("large count lost precision: %#v", count)
+	}
+}
+
+func TestMapStatusToError(t *testing.T) {
+	body := []byte(`{"errors":[{"code":10001,"message":"something went wrong"}]}`)
+	cases := []struct {
+		status   int
+		expected error
+	}{
+		{400, verror.ServerBadDataResponce},
+		{401, verror.AuthError},
+		{403, verror.AuthError},
+		{404, verror.ServerError},
+		{409, verror.ServerConflictError},
+		{500, verror.ServerTemporaryUnavailableError},
+		{502, verror.ServerTemporaryUnavailableError},
+		{503, verror.ServerTemporaryUnavailableError},
+	}
+	for _, c := range cases {
+		err := mapStatusToError(c.status, body)
+		if !errors.Is(err, c.expected) {
+			t.Errorf("status %d: expected %q, got %q", c.status, c.expected, err)
+		}
+		if !strings.Contains(err.Error(), "something went wrong") {
+			t.Errorf("status %d: expected server message in error, got %q", c.status, err)
+		}
+	}
+	if err := mapStatusToError(401, nil); !errors.Is(err, verror.AuthError) || !strings.Contains(err.Error(), "<empty>") {
+		t.Errorf("expected auth error for empty body, got %q", err)
+	}
+	if errors.Is(mapStatusToError(404, body), verror.ServerTemporaryUnavailableError) {
+		t.Error("404 should not be reported as temporary")
+	}
+}
+
+func TestMockCloudAPIError(t *testing.T) {
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors":[{"code":10501,"message":"Unable to find certificate request"},{"code":10502,"message":"Second error"}]}`))
+	})
+	defer server.Close()
+
+	_, err := conn.getCertificateStatus("r1")
+	var apiErr *CloudAPIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected CloudAPIError, got %T: %v", err, err)
+	}
+	if apiErr.StatusCode != http.StatusNotFound || len(apiErr.Errors) != 2 || !apiErr.HasCode(10501) || apiErr.HasCode(10001) {
+		t.Fatalf("unexpected error details %+v", apiErr)
+	}
+	if apiErr.Errors[1].Message != "Second error" {
+		t.Fatalf("unexpected error message %q", apiErr.Errors[1].Message)
+	}
+	if !errors.Is(err, verror.ServerError) || !strings.Contains(err.Error(), "Error Code: 10501 Error: Unable to find certificate request") {
+		t.Fatalf("unexpected error %q", err)
+	}
+
+	_, err = conn.getCertificate("c1")
+	if !errors.As(err, &apiErr) || !apiErr.HasCode(10502) {
+		t.Fatalf("expected CloudAPIError, got %T: %v", err, err)
+	}
+}
+
+func TestMockAPIKeyRedacted(t *testing.T) {
+	const key = "0a1b2c3d-4e5f-6a7b-8c9d-0e1f2a3b4c5d"
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"errors":[{"code":10501,"message":"Invalid api key ` + r.Header.Get("tppl-api-key") + `"}]}`))
+	})
+	defer server.Close()
+	logger := &recordingLogger{}
+	conn.SetLogger(logger)
+
+	err := conn.Authenticate(&endpoint.Authentication{APIKey: key})
+	if !errors.Is(err, verror.AuthError) {
+		t.Fatalf("expected auth error, got %v", err)
+	}
+	if strings.Contains(err.Error(), key) || !strings.Contains(err.Error(), redacted) {
+		t.Fatalf("expected the API key to be masked in the error, got %s", err)
+	}
+	for level, messages := range logger.messages {
+		for _, m := range messages {
+			if strings.Contains(m, key) {
+				t.Fatalf("API key logged at %s level: %s", level, m)
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+// Health check names used as keys of HealthStatus.Errors
+const (
+	HealthCheckPing  = "ping"
+	HealthCheckAuth  = "auth"
+	HealthCheckQuota = "quota"
+)
+
+// HealthStatus is the result of Health. Every check is done independently, so one failing check doesn't hide the others.
+type HealthStatus struct {
+	Reachable     bool   `json:"reachable"`
+	Authenticated bool   `json:"authenticated"`
+	CompanyID     string `json:"companyId,omitempty"`
+	// RemainingQuota is -1 when the quota is unknown, e.g. because the server doesn't report it
+	RemainingQuota int `json:"remainingQuota"`
+	// Errors maps names of failed checks to their errors
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// Health checks the connector readiness: whether the server is reachable, the API key is still valid and
+// how much issuance quota is left. It's meant to be served as is by a health endpoint.
+func (c *Connector) Health() HealthStatus {
+	status := HealthStatus{RemainingQuota: -1}
+	fail := func(check string, err error) {
+		if status.Errors == nil {
+			status.Errors = make(map[string]string)
+		}
+		status.Errors[check] = err.Error()
+	}
+
+	if err := c.Ping(); err != nil {
+		fail(HealthCheckPing, err)
+	} else {
+		status.Reachable = true
+	}
+
+	if ud, err := c.whoAmI(); err != nil {
+		fail(HealthCheckAuth, err)
+	} else {
+		status.Authenticated = true
+		status.CompanyID = ud.Company.ID
+	}
+
+	if quota, err := c.remainingQuota(); err != nil {
+		fail(HealthCheckQuota, err)
+	} else {
+		status.RemainingQuota = quota
+	}
+	return status
+}
+
+// whoAmI reads the details of the user owning the API key, it fails if the key is no longer valid
+func (c *Connector) whoAmI() (*userDetails, error) {
+	if c.getAPIKey() == "" {
+		return nil, fmt.Errorf("%w: API key is not set", verror.AuthError)
+	}
+	statusCode, status, body, err := c.request("GET", c.getURL(urlResourceUserAccounts), nil, true)
+	if err != nil {
+		return nil, err
+	}
+	ud, err := parseUserDetailsResult(http.StatusOK, statusCode, status, body)
+	if err != nil {
+		return nil, err
+	}
+	if ud.Company == nil || ud.Company.ID == "" {
+		return nil, fmt.Errorf("%w: user details don't contain a company", verror.AuthError)
+	}
+	return ud, nil
+}
+
+// remainingQuota returns the number of certificates that can still be issued, -1 when it's unknown.
+// Venafi Cloud API used by the connector doesn't report the issuance quota, so it's always unknown for now,
+// which is not a failure of the check.
+func (c *Connector) remainingQuota() (int, error) {
+	return -1, nil
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+func TestMockHealth(t *testing.T) {
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/"+string(urlResourceUserAccounts) && r.Header.Get(headerNameAPIKey) == "mock-api-key" {
+			_, _ = w.Write(successGetUserAccount)
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	defer server.Close()
+
+	status := conn.Health()
+	if !status.Reachable || !status.Authenticated {
+		t.Fatalf("expected reachable and authenticated connector, got %+v", status)
+	}
+	if status.CompanyID != "a94d5140-efaf-11e5-b223-d96cf8021ce5" {
+		t.Fatalf("unexpected company ID %s", status.CompanyID)
+	}
+	// the quota isn't reported by the server, which is not an error
+	if status.RemainingQuota != -1 || len(status.Errors) != 0 {
+		t.Fatalf("expected healthy connector with unknown quota, got %+v", status)
+	}
+
+	conn.apiKey = "revoked-api-key"
+	status = conn.Health()
+	if status.Authenticated || status.Errors[HealthCheckAuth] == "" || !status.Reachable {
+		t.Fatalf("expected failed auth check only, got %+v", status)
+	}
+}
+
+func TestMockPing(t *testing.T) {
+	var statusCode int
+	var apiKey string
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+string(urlResourceUserAccounts) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		apiKey = r.Header.Get(headerNameAPIKey)
+		w.WriteHeader(statusCode)
+	})
+	defer server.Close()
+	conn.apiKey = ""
+	conn.user = nil
+
+	for _, code := range []int{http.StatusOK, http.StatusUnauthorized} {
+		statusCode = code
+		if err := conn.Ping(); err != nil {
+			t.Fatalf("expected reachable server for status %d, got %s", code, err)
+		}
+	}
+	if apiKey != "" {
+		t.Fatalf("unexpected API key %q sent before authentication", apiKey)
+	}
+
+	statusCode = http.StatusServiceUnavailable
+	if err := conn.Ping(); !errors.Is(err, verror.ServerTemporaryUnavailableError) {
+		t.Fatalf("expected temporary unavailable error for a 5xx response, got %v", err)
+	}
+
+	untrusted, err := NewConnector(server.URL, "", false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	untrusted.SetMaxRetries(0)
+	if err = untrusted.Ping(); !errors.Is(err, verror.ServerTemporaryUnavailableError) {
+		t.Fatalf("expected temporary unavailable error for a TLS failure, got %v", err)
+	}
+
+	server.Close()
+	if err = conn.Ping(); !errors.Is(err, verror.ServerTemporaryUnavailableError) {
+		t.Fatalf("expected temporary unavailable error for an unreachable server, got %v", err)
+	}
+}
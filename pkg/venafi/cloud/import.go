@@ -0,0 +1,215 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+const importBatchSize = 50
+
+var defaultImportExtensions = []string{".pem", ".crt", ".cer"}
+
+// ImportDirOptions controls how ImportFromDir picks and imports files
+type ImportDirOptions struct {
+	// PolicyDN is the application ID to import certificates to. The application of the connector zone is used when empty.
+	PolicyDN string
+	// Extensions lists the file extensions to import. Defaults to .pem, .crt and .cer
+	Extensions   []string
+	CustomFields []certificate.CustomField
+}
+
+// ImportFileResult is the result of importing a single file by ImportFromDir
+type ImportFileResult struct {
+	File        string
+	Fingerprint string
+	CertId      string
+	// Skipped is true when the file doesn't contain any certificate
+	Skipped bool
+	Err     error
+}
+
+// ImportFromDir imports every certificate file found in the directory (not recursive).
+// Files containing a chain are imported as the first certificate with the rest used as issuer certificates.
+// Per-file failures are reported in the results; the returned error is only set when the import can't be performed at all.
+func (c *Connector) ImportFromDir(path string, opts ImportDirOptions) ([]ImportFileResult, error) {
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", verror.UserDataError, err)
+	}
+	extensions := opts.Extensions
+	if len(extensions) == 0 {
+		extensions = defaultImportExtensions
+	}
+
+	appID := opts.PolicyDN
+	if appID == "" {
+		appDetails, err := c.zoneAppDetails()
+		if err != nil {
+			return nil, err
+		}
+		appID = appDetails.ApplicationId
+	}
+	origin := clientOrigin("", opts.CustomFields)
+	ipAddr := c.outboundIP()
+
+	var results []ImportFileResult
+	var infos []importRequestCertInfo
+	var pending []int
+	for _, f := range files {
+		if f.IsDir() || !hasExtension(f.Name(), extensions) {
+			continue
+		}
+		result := ImportFileResult{File: filepath.Join(path, f.Name())}
+		data, err := ioutil.ReadFile(result.File)
+		if err != nil {
+			result.Err = err
+			results = append(results, result)
+			continue
+		}
+		certs := certificateBlocks(data)
+		if len(certs) == 0 {
+			result.Skipped = true
+			results = append(results, result)
+			continue
+		}
+		info := importRequestCertInfo{
+			Certificate:    base64.StdEncoding.EncodeToString(certs[0]),
+			ApplicationIds: []string{appID},
+			ApiClientInformation: apiClientInformation{
+				Type:       origin,
+				Identifier: ipAddr,
+			},
+		}
+		for _, issuer := range certs[1:] {
+			info.IssuerCertificates = append(info.IssuerCertificates, base64.StdEncoding.EncodeToString(issuer))
+		}
+		result.Fingerprint = certThumbprint(certs[0])
+		infos = append(infos, info)
+		pending = append(pending, len(results))
+		results = append(results, result)
+	}
+
+	for start := 0; start < len(infos); start += importBatchSize {
+		end := start + importBatchSize
+		if end > len(infos) {
+			end = len(infos)
+		}
+		r, err := c.postImportRequest(importRequest{Certificates: infos[start:end]})
+		imported := make(map[string]string)
+		if err == nil {
+			for _, ci := range r.CertificateInformations {
+				imported[normalizeFingerprint(ci.Fingerprint)] = ci.Id
+			}
+		}
+		for _, i := range pending[start:end] {
+			switch id, ok := imported[results[i].Fingerprint]; {
+			case err != nil:
+				results[i].Err = err
+			case !ok:
+				results[i].Err = fmt.Errorf("%w: certificate was not imported on unknown reason", verror.ServerBadDataResponce)
+			default:
+				results[i].CertId = id
+			}
+		}
+	}
+	return results, nil
+}
+
+func hasExtension(name string, extensions []string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range extensions {
+		if ext == strings.ToLower(e) {
+			return true
+		}
+	}
+	return false
+}
+
+// certificateBlocks returns DER bytes of all certificates found in PEM data
+func certificateBlocks(data []byte) [][]byte {
+	var certs [][]byte
+	for {
+		var p *pem.Block
+		p, data = pem.Decode(data)
+		if p == nil {
+			return certs
+		}
+		if p.Type == "CERTIFICATE" {
+			certs = append(certs, p.Bytes)
+		}
+	}
+}
+
+// checkImportPrivateKey checks that the PEM private key, encrypted with the password when it's set, belongs to the certificate
+func checkImportPrivateKey(keyPEM string, password string, cert []byte) error {
+	b, _ := pem.Decode([]byte(keyPEM))
+	if b == nil {
+		return fmt.Errorf("%w: invalid private key PEM", verror.UserDataError)
+	}
+	der := b.Bytes
+	//nolint:staticcheck
+	if x509.IsEncryptedPEMBlock(b) {
+		var err error
+		//nolint:staticcheck
+		if der, err = x509.DecryptPEMBlock(b, []byte(password)); err != nil {
+			return fmt.Errorf("%w: failed to decrypt private key: %v", verror.UserDataError, err)
+		}
+	}
+	var key interface{}
+	var err error
+	switch b.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(der)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(der)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(der)
+	default:
+		return fmt.Errorf("%w: unsupported private key PEM type %s", verror.UserDataError, b.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: failed to parse private key: %v", verror.UserDataError, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return fmt.Errorf("%w: unsupported private key type %T", verror.UserDataError, key)
+	}
+	parsed, err := x509.ParseCertificate(cert)
+	if err != nil {
+		return fmt.Errorf("%w: failed to parse certificate: %v", verror.UserDataError, err)
+	}
+	keyPublic, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return fmt.Errorf("%w: unsupported private key: %v", verror.UserDataError, err)
+	}
+	if !bytes.Equal(keyPublic, parsed.RawSubjectPublicKeyInfo) {
+		return fmt.Errorf("%w: private key doesn't match the public key of certificate %s", verror.UserDataError, parsed.Subject.CommonName)
+	}
+	return nil
+}
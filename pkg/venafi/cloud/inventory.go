@@ -0,0 +1,177 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+// inventorySANDelimiter joins multiple SANs in a single CSV column
+const inventorySANDelimiter = ";"
+
+var inventoryCSVHeader = []string{"cn", "sans", "fingerprint", "issuer", "valid_from", "valid_to", "id"}
+
+// ExportInventoryCSV writes the certificates of the zone matching the filter as CSV, one row per certificate.
+// Certificates are written page by page as they are fetched, so the whole inventory is never kept in memory.
+func (c *Connector) ExportInventoryCSV(w io.Writer, filter endpoint.Filter) error {
+	if c.currentZone().String() == "" {
+		return fmt.Errorf("empty zone")
+	}
+	const batchSize = 50
+	limit := -1
+	if filter.Limit != nil {
+		limit = *filter.Limit
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(inventoryCSVHeader); err != nil {
+		return err
+	}
+	for page := 0; limit != 0; page++ {
+		certs, err := c.searchCertsBatch(page, batchSize, filter)
+		if err != nil {
+			return err
+		}
+		for _, cert := range certs {
+			if limit == 0 {
+				break
+			}
+			if err = cw.Write(inventoryRow(cert)); err != nil {
+				return err
+			}
+			limit--
+		}
+		cw.Flush()
+		if err = cw.Error(); err != nil {
+			return err
+		}
+		if len(certs) < batchSize {
+			break
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func inventoryRow(cert Certificate) []string {
+	info := cert.ToCertificateInfo()
+	var sans []string
+	for _, names := range [][]string{info.SANS.DNS, info.SANS.IP, info.SANS.Email, info.SANS.URI} {
+		sans = append(sans, names...)
+	}
+	return []string{
+		info.CN,
+		strings.Join(sans, inventorySANDelimiter),
+		info.Thumbprint,
+		strings.Join(cert.IssuerCN, inventorySANDelimiter),
+		formatInventoryTime(info.ValidFrom),
+		formatInventoryTime(info.ValidTo),
+		info.ID,
+	}
+}
+
+func formatInventoryTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// maxIssuerScanCertificates caps the number of certificates GetZoneIssuers looks at
+const maxIssuerScanCertificates = 5000
+
+// GetZoneIssuers returns the distinct issuer common names of the zone certificates, including expired ones.
+// The search API doesn't aggregate issuers, so certificates are scanned page by page. The scan stops after
+// maxIssuerScanCertificates certificates, so the result may be incomplete for very large zones.
+func (c *Connector) GetZoneIssuers(zone string) ([]string, error) {
+	conn := c.snapshot()
+	conn.SetZone(zone)
+	if err := conn.zone.parseZone(); err != nil {
+		return nil, fmt.Errorf("%w: %v", verror.UserDataError, err)
+	}
+	const batchSize = 50
+	seen := make(map[string]bool)
+	var issuers []string
+	for page := 0; page*batchSize < maxIssuerScanCertificates; page++ {
+		certs, err := conn.searchCertsBatch(page, batchSize, endpoint.Filter{WithExpired: true})
+		if err != nil {
+			return nil, err
+		}
+		for _, cert := range certs {
+			for _, issuer := range cert.IssuerCN {
+				if !seen[issuer] {
+					seen[issuer] = true
+					issuers = append(issuers, issuer)
+				}
+			}
+		}
+		if len(certs) < batchSize {
+			break
+		}
+	}
+	sort.Strings(issuers)
+	return issuers, nil
+}
+
+// FindDuplicateSANs groups the valid certificates of the zone by common name and DNS SAN (case-insensitive)
+// and returns the names shared by more than one certificate, e.g. to find shadow certificates.
+// The zone is read page by page, but a certificate is kept under each of its names until the whole zone is read,
+// so the memory used grows with the inventory.
+func (c *Connector) FindDuplicateSANs(zone string) (map[string][]certificate.CertificateInfo, error) {
+	conn := c.snapshot()
+	conn.SetZone(zone)
+	if err := conn.zone.parseZone(); err != nil {
+		return nil, fmt.Errorf("%w: %v", verror.UserDataError, err)
+	}
+	const batchSize = 50
+	groups := make(map[string][]certificate.CertificateInfo)
+	for page := 0; ; page++ {
+		certs, err := conn.searchCertsBatch(page, batchSize, endpoint.Filter{})
+		if err != nil {
+			return nil, err
+		}
+		for _, cert := range certs {
+			info := cert.ToCertificateInfo()
+			names := make(map[string]bool)
+			for _, name := range append([]string{info.CN}, info.SANS.DNS...) {
+				if name != "" {
+					names[strings.ToLower(name)] = true
+				}
+			}
+			for name := range names {
+				groups[name] = append(groups[name], info)
+			}
+		}
+		if len(certs) < batchSize {
+			break
+		}
+	}
+	for name, infos := range groups {
+		if len(infos) < 2 {
+			delete(groups, name)
+		}
+	}
+	return groups, nil
+}
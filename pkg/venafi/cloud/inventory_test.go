@@ -0,0 +1,130 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+)
+
+func TestMockExportInventoryCSV(t *testing.T) {
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + basePath + "applications/name/App":
+			_, _ = w.Write(successGetAppDetails)
+		case "/" + string(urlResourceCertificateSearch):
+			_, _ = w.Write([]byte(`{"count":1,"certificates":[{"id":"c1","subjectCN":["inventory.vfidev.com"],` +
+				`"subjectAlternativeNamesByType":{"dNSName":["inventory.vfidev.com","www.vfidev.com"],"iPAddress":["10.0.0.1"]},` +
+				`"fingerprint":"A7BDECDA0B67D5CEF28D6C8C7D7CFA882E3DC9D6","issuerCN":["Mock CA"],` +
+				`"validityStart":"2021-01-01T00:00:00+0000","validityEnd":"2022-01-01T00:00:00+0000"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	var buf bytes.Buffer
+	if err := conn.ExportInventoryCSV(&buf, endpoint.Filter{WithExpired: true}); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	expected := "cn,sans,fingerprint,issuer,valid_from,valid_to,id\n" +
+		"inventory.vfidev.com,inventory.vfidev.com;www.vfidev.com;10.0.0.1,A7BDECDA0B67D5CEF28D6C8C7D7CFA882E3DC9D6,Mock CA,2021-01-01T00:00:00Z,2022-01-01T00:00:00Z,c1\n"
+	if buf.String() != expected {
+		t.Fatalf("unexpected CSV\nget:\n%s\nexpect:\n%s", buf.String(), expected)
+	}
+}
+
+func TestMockGetZoneIssuers(t *testing.T) {
+	var pages []int
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + basePath + "applications/name/Other":
+			_, _ = w.Write(successGetAppDetails)
+		case "/" + string(urlResourceCertificateSearch):
+			var req SearchRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			pages = append(pages, req.Paging.PageNumber)
+			if req.Paging.PageNumber > 0 {
+				_, _ = w.Write([]byte(`{"count":0,"certificates":[{"id":"c51","issuerCN":["Other CA"]}]}`))
+				return
+			}
+			var certs []string
+			for i := 0; i < 50; i++ {
+				issuer := "Mock CA"
+				if i%2 == 0 {
+					issuer = "Another CA"
+				}
+				certs = append(certs, fmt.Sprintf(`{"id":"c%d","issuerCN":["%s"]}`, i, issuer))
+			}
+			_, _ = fmt.Fprintf(w, `{"count":51,"certificates":[%s]}`, strings.Join(certs, ","))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	issuers, err := conn.GetZoneIssuers("Other\\Template")
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	expected := []string{"Another CA", "Mock CA", "Other CA"}
+	if !reflect.DeepEqual(issuers, expected) {
+		t.Fatalf("unexpected issuers\nget:    %v\nexpect: %v", issuers, expected)
+	}
+	if !reflect.DeepEqual(pages, []int{0, 1}) {
+		t.Fatalf("unexpected pages requested %v", pages)
+	}
+	if conn.zone.String() != mockZone {
+		t.Fatalf("connector zone was changed to %s", conn.zone)
+	}
+}
+
+func TestMockFindDuplicateSANs(t *testing.T) {
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + basePath + "applications/name/App":
+			_, _ = w.Write(successGetAppDetails)
+		case "/" + string(urlResourceCertificateSearch):
+			_, _ = w.Write([]byte(`{"count":3,"certificates":[` +
+				`{"id":"c1","subjectCN":["www.vfidev.com"],"subjectAlternativeNamesByType":{"dNSName":["www.vfidev.com","api.vfidev.com"]}},` +
+				`{"id":"c2","subjectCN":["shadow"],"subjectAlternativeNamesByType":{"dNSName":["API.vfidev.com"]}},` +
+				`{"id":"c3","subjectCN":["mail.vfidev.com"]}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	groups, err := conn.FindDuplicateSANs(mockZone)
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if len(groups) != 1 {
+		t.Fatalf("expected a single duplicate name, got %v", groups)
+	}
+	infos := groups["api.vfidev.com"]
+	if len(infos) != 2 || infos[0].ID != "c1" || infos[1].ID != "c2" {
+		t.Fatalf("unexpected duplicates for api.vfidev.com: %v", infos)
+	}
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+)
+
+const urlResourceCertificateKeystore = urlResourceCertificates + "/%s/keystore"
+
+// csrAttributes replace the CSR of a request when Venafi Cloud generates the key
+type csrAttributes struct {
+	CommonName                    string             `json:"commonName,omitempty"`
+	Organization                  string             `json:"organization,omitempty"`
+	OrganizationalUnits           []string           `json:"organizationalUnits,omitempty"`
+	Locality                      string             `json:"locality,omitempty"`
+	State                         string             `json:"state,omitempty"`
+	Country                       string             `json:"country,omitempty"`
+	SubjectAlternativeNamesByType *sansByType        `json:"subjectAlternativeNamesByType,omitempty"`
+	KeyTypeParameters             *keyTypeParameters `json:"keyTypeParameters,omitempty"`
+}
+
+type sansByType struct {
+	DNSNames                   []string `json:"dnsNames,omitempty"`
+	IPAddresses                []string `json:"ipAddresses,omitempty"`
+	RFC822Names                []string `json:"rfc822Names,omitempty"`
+	UniformResourceIdentifiers []string `json:"uniformResourceIdentifiers,omitempty"`
+}
+
+type keyTypeParameters struct {
+	KeyType   string `json:"keyType"`
+	KeyLength int    `json:"keyLength,omitempty"`
+	KeyCurve  string `json:"keyCurve,omitempty"`
+}
+
+type keystoreRequest struct {
+	ExportFormat         string `json:"exportFormat"`
+	PrivateKeyPassphrase string `json:"privateKeyPassphrase,omitempty"`
+}
+
+func newCSRAttributes(req *certificate.Request) *csrAttributes {
+	first := func(values []string) string {
+		if len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	attributes := &csrAttributes{
+		CommonName:          req.Subject.CommonName,
+		Organization:        first(req.Subject.Organization),
+		OrganizationalUnits: req.Subject.OrganizationalUnit,
+		Locality:            first(req.Subject.Locality),
+		State:               first(req.Subject.Province),
+		Country:             first(req.Subject.Country),
+	}
+	sans := &sansByType{DNSNames: req.DNSNames, RFC822Names: req.EmailAddresses}
+	for _, ip := range req.IPAddresses {
+		sans.IPAddresses = append(sans.IPAddresses, ip.String())
+	}
+	for _, uri := range req.URIs {
+		sans.UniformResourceIdentifiers = append(sans.UniformResourceIdentifiers, uri.String())
+	}
+	if len(sans.DNSNames)+len(sans.IPAddresses)+len(sans.RFC822Names)+len(sans.UniformResourceIdentifiers) > 0 {
+		attributes.SubjectAlternativeNamesByType = sans
+	}
+	switch req.KeyType {
+	case certificate.KeyTypeECDSA:
+		attributes.KeyTypeParameters = &keyTypeParameters{KeyType: "EC", KeyCurve: req.KeyCurve.String()}
+	default:
+		attributes.KeyTypeParameters = &keyTypeParameters{KeyType: "RSA", KeyLength: req.KeyLength}
+	}
+	return attributes
+}
+
+// fetchPrivateKey downloads the PEM private key generated by Venafi Cloud for the certificate.
+// The key is encrypted with the password when it's not empty.
+func (c *Connector) fetchPrivateKey(certificateID string, password string) (string, error) {
+	url := fmt.Sprintf(c.getURL(urlResourceCertificateKeystore), certificateID)
+	statusCode, _, body, err := c.request("POST", url, keystoreRequest{ExportFormat: "PEM", PrivateKeyPassphrase: password})
+	if err != nil {
+		return "", err
+	}
+	if statusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to retrieve private key: %w", mapStatusToError(statusCode, body))
+	}
+	pcc, err := certificate.PEMCollectionFromBytes(body, certificate.ChainOptionIgnore)
+	if err != nil {
+		return "", err
+	}
+	if pcc.PrivateKey == "" {
+		return "", fmt.Errorf("failed to retrieve private key: no private key in the response")
+	}
+	return pcc.PrivateKey, nil
+}
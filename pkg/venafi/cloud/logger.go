@@ -0,0 +1,80 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Logger receives the log messages of the connector, e.g. to route them to a structured logger.
+// Debugf gets the traces of the HTTP requests and responses, with the credentials redacted.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// SetLogger sets the logger of the connector. All the messages go to it whether the connector is verbose or not,
+// a nil logger restores the default of writing to the standard logger when verbose.
+func (c *Connector) SetLogger(logger Logger) {
+	c.logger = logger
+}
+
+func (c *Connector) getLogger() Logger {
+	switch {
+	case c.logger != nil:
+		return c.logger
+	case c.verbose:
+		return stdLogger{}
+	default:
+		return nopLogger{}
+	}
+}
+
+// stdLogger writes all the messages to the standard logger
+type stdLogger struct{}
+
+func (stdLogger) Debugf(format string, args ...interface{}) { log.Printf(format, args...) }
+func (stdLogger) Infof(format string, args ...interface{})  { log.Printf(format, args...) }
+func (stdLogger) Errorf(format string, args ...interface{}) { log.Printf(format, args...) }
+
+type nopLogger struct{}
+
+func (nopLogger) Debugf(string, ...interface{}) {}
+func (nopLogger) Infof(string, ...interface{})  {}
+func (nopLogger) Errorf(string, ...interface{}) {}
+
+// redactedHeaders formats the headers for logging with the values of the reserved headers masked
+func redactedHeaders(header http.Header) string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		value := strings.Join(header[name], ", ")
+		if isReservedHeader(name) {
+			value = redacted
+		}
+		parts = append(parts, name+": "+value)
+	}
+	return strings.Join(parts, "; ")
+}
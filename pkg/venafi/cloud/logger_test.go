@@ -0,0 +1,76 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+)
+
+type recordingLogger struct {
+	mu       sync.Mutex
+	messages map[string][]string
+}
+
+func (l *recordingLogger) record(level string, format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.messages == nil {
+		l.messages = make(map[string][]string)
+	}
+	l.messages[level] = append(l.messages[level], fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Debugf(format string, args ...interface{}) {
+	l.record("debug", format, args...)
+}
+func (l *recordingLogger) Infof(format string, args ...interface{}) {
+	l.record("info", format, args...)
+}
+func (l *recordingLogger) Errorf(format string, args ...interface{}) {
+	l.record("error", format, args...)
+}
+
+func TestMockSetLogger(t *testing.T) {
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(successGetUserAccount)
+	})
+	defer server.Close()
+	logger := &recordingLogger{}
+	conn.SetLogger(logger)
+
+	if err := conn.Authenticate(&endpoint.Authentication{APIKey: "mock-api-key"}); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	debug := strings.Join(logger.messages["debug"], "\n")
+	if !strings.Contains(debug, "Sending GET "+conn.getURL(urlResourceUserAccounts)) || !strings.Contains(debug, "Got 200 OK status") {
+		t.Fatalf("expected the request and response to be traced, got %q", debug)
+	}
+	if strings.Contains(debug, "mock-api-key") || !strings.Contains(debug, "Tppl-Api-Key: "+redacted) {
+		t.Fatalf("expected the API key to be redacted, got %q", debug)
+	}
+
+	conn.SetZone("malformed")
+	if len(logger.messages["error"]) != 1 {
+		t.Fatalf("expected the malformed zone to be reported, got %v", logger.messages["error"])
+	}
+}
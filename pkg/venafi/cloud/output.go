@@ -0,0 +1,142 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+// OutputLayout controls the files WriteToDir writes. Files with an empty name aren't written.
+type OutputLayout struct {
+	CertFile  string
+	ChainFile string
+	KeyFile   string
+	// CombineChain appends the chain to the certificate file
+	CombineChain bool
+	// CombineKey appends the private key to the certificate file
+	CombineKey bool
+}
+
+var (
+	// LayoutFullChain writes fullchain.pem with the certificate and its chain and privkey.pem,
+	// the files most web servers and ACME clients use. It's the default layout.
+	LayoutFullChain = OutputLayout{CertFile: "fullchain.pem", KeyFile: "privkey.pem", CombineChain: true}
+	// LayoutSeparate writes the certificate, the chain and the private key to separate files
+	LayoutSeparate = OutputLayout{CertFile: "cert.pem", ChainFile: "chain.pem", KeyFile: "privkey.pem"}
+	// LayoutBundle writes the certificate, the chain and the private key to a single file, e.g. for HAProxy
+	LayoutBundle = OutputLayout{CertFile: "bundle.pem", CombineChain: true, CombineKey: true}
+)
+
+// RequestAndRetrieveToDir works like RequestAndRetrieve and writes the certificate to dir with the layout.
+// The directory is checked to be writable before the certificate is requested. A zero layout means LayoutFullChain.
+func (c *Connector) RequestAndRetrieveToDir(req *certificate.Request, dir string, layout OutputLayout) (*certificate.PEMCollection, error) {
+	if err := checkWritableDir(dir); err != nil {
+		return nil, err
+	}
+	pcc, err := c.RequestAndRetrieve(req)
+	if err != nil {
+		return pcc, err
+	}
+	if pcc.PrivateKey == "" && req.PrivateKey != nil {
+		if err = pcc.AddPrivateKey(req.PrivateKey, []byte(req.KeyPassword)); err != nil {
+			return pcc, err
+		}
+	}
+	return pcc, WriteToDir(pcc, dir, layout)
+}
+
+// WriteToDir writes the PEM collection to dir with the layout. A zero layout means LayoutFullChain.
+// Files containing the private key are only readable by the owner.
+func WriteToDir(pcc *certificate.PEMCollection, dir string, layout OutputLayout) error {
+	if layout == (OutputLayout{}) {
+		layout = LayoutFullChain
+	}
+	if layout.CertFile == "" {
+		return fmt.Errorf("%w: output layout must name the certificate file", verror.UserDataError)
+	}
+	cert := []string{pcc.Certificate}
+	if layout.CombineChain {
+		cert = append(cert, pcc.Chain...)
+	}
+	certMode := os.FileMode(0644)
+	if layout.CombineKey && pcc.PrivateKey != "" {
+		cert = append(cert, pcc.PrivateKey)
+		certMode = 0600
+	}
+	if err := writePEMFile(dir, layout.CertFile, cert, certMode); err != nil {
+		return err
+	}
+	if layout.ChainFile != "" && !layout.CombineChain && len(pcc.Chain) > 0 {
+		if err := writePEMFile(dir, layout.ChainFile, pcc.Chain, 0644); err != nil {
+			return err
+		}
+	}
+	if layout.KeyFile != "" && !layout.CombineKey && pcc.PrivateKey != "" {
+		if err := writePEMFile(dir, layout.KeyFile, []string{pcc.PrivateKey}, 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writePEMFile(dir, name string, blocks []string, mode os.FileMode) error {
+	var b strings.Builder
+	for _, block := range blocks {
+		b.WriteString(strings.TrimSpace(block))
+		b.WriteString("\n")
+	}
+	path := filepath.Join(dir, name)
+	// the content is written to a new file which has the mode before it has any content and replaces the
+	// existing file, whose permissions may be wider
+	f, err := ioutil.TempFile(dir, ".vcert-")
+	if err != nil {
+		return fmt.Errorf("%w: failed to write %s: %v", verror.UserDataError, path, err)
+	}
+	defer os.Remove(f.Name())
+	if err = f.Chmod(mode); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("%w: failed to set permissions of %s: %v", verror.UserDataError, path, err)
+	}
+	_, err = f.WriteString(b.String())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("%w: failed to write %s: %v", verror.UserDataError, path, err)
+	}
+	if err = os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("%w: failed to write %s: %v", verror.UserDataError, path, err)
+	}
+	return nil
+}
+
+// checkWritableDir creates and removes a temporary file, so a request isn't made just to fail writing the result
+func checkWritableDir(dir string) error {
+	f, err := ioutil.TempFile(dir, ".vcert-")
+	if err != nil {
+		return fmt.Errorf("%w: output directory %s is not writable: %v", verror.UserDataError, dir, err)
+	}
+	_ = f.Close()
+	return os.Remove(f.Name())
+}
@@ -0,0 +1,150 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+var testOutputPCC = &certificate.PEMCollection{Certificate: "CERT\n", Chain: []string{"ICA\n", "ROOT\n"}, PrivateKey: "KEY\n"}
+
+func readOutputFile(t *testing.T, dir, name string, mode os.FileMode) string {
+	path := filepath.Join(dir, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if info.Mode().Perm() != mode {
+		t.Fatalf("expected %s to have mode %s, got %s", name, mode, info.Mode().Perm())
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	return string(b)
+}
+
+func TestOfflineWriteToDirSeparate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vcert-output")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err = WriteToDir(testOutputPCC, dir, LayoutSeparate); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if s := readOutputFile(t, dir, "cert.pem", 0644); s != "CERT\n" {
+		t.Fatalf("unexpected certificate file %q", s)
+	}
+	if s := readOutputFile(t, dir, "chain.pem", 0644); s != "ICA\nROOT\n" {
+		t.Fatalf("unexpected chain file %q", s)
+	}
+	if s := readOutputFile(t, dir, "privkey.pem", 0600); s != "KEY\n" {
+		t.Fatalf("unexpected key file %q", s)
+	}
+}
+
+func TestOfflineWriteToDirExistingKeyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vcert-output")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "privkey.pem")
+	if err = ioutil.WriteFile(path, []byte("OLD\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	existing, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = WriteToDir(testOutputPCC, dir, LayoutSeparate); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if s := readOutputFile(t, dir, "privkey.pem", 0600); s != "KEY\n" {
+		t.Fatalf("unexpected key file %q", s)
+	}
+	// the key is never written to the world readable file, it's replaced
+	replaced, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if os.SameFile(existing, replaced) {
+		t.Fatalf("expected the existing key file to be replaced instead of written")
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 3 {
+		t.Fatalf("expected no temporary files to be left, got %d files", len(files))
+	}
+}
+
+func TestOfflineWriteToDirBundle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vcert-output")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err = WriteToDir(testOutputPCC, dir, LayoutBundle); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if s := readOutputFile(t, dir, "bundle.pem", 0600); s != "CERT\nICA\nROOT\nKEY\n" {
+		t.Fatalf("unexpected bundle file %q", s)
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("expected only the bundle file, got %d files", len(files))
+	}
+
+	// the zero layout is the full chain one
+	if err = WriteToDir(testOutputPCC, dir, OutputLayout{}); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if s := readOutputFile(t, dir, "fullchain.pem", 0644); s != "CERT\nICA\nROOT\n" {
+		t.Fatalf("unexpected full chain file %q", s)
+	}
+}
+
+func TestMockRequestAndRetrieveToDirNotWritable(t *testing.T) {
+	requests := 0
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNotFound)
+	})
+	defer server.Close()
+
+	_, err := conn.RequestAndRetrieveToDir(&certificate.Request{}, filepath.Join(os.TempDir(), "vcert-missing", "dir"), LayoutSeparate)
+	if !errors.Is(err, verror.UserDataError) {
+		t.Fatalf("expected user data error, got %v", err)
+	}
+	if requests != 0 {
+		t.Fatalf("expected no requests before the directory is validated, got %d", requests)
+	}
+}
@@ -0,0 +1,52 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+// checkRequestPolicy validates the CSR of the request against the policy of the zone, so that requests the
+// issuing template would reject fail early with the offending field instead of a generic server error.
+// The check is skipped when the request has no CSR or the issuing template cannot be read.
+func (c *Connector) checkRequestPolicy(req *certificate.Request) error {
+	if req.CsrOrigin == certificate.ServiceGeneratedCSR || len(req.GetCSR()) == 0 {
+		return nil
+	}
+	b, _ := pem.Decode(req.GetCSR())
+	if b == nil {
+		return fmt.Errorf("%w: failed to decode CSR PEM", verror.UserDataError)
+	}
+	if _, err := x509.ParseCertificateRequest(b.Bytes); err != nil {
+		return fmt.Errorf("%w: failed to parse CSR: %v", verror.UserDataError, err)
+	}
+	template, err := c.getTemplateByID()
+	if err != nil {
+		c.getLogger().Infof("skipping policy check, failed to read issuing template of zone %s: %v", c.currentZone(), err)
+		return nil
+	}
+	policy := template.toPolicy()
+	if err = policy.ValidateCertificateRequest(req); err != nil {
+		return fmt.Errorf("%w: CSR doesn't match the policy of zone %s: %v", verror.UserDataError, c.currentZone(), err)
+	}
+	return nil
+}
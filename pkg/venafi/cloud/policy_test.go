@@ -0,0 +1,141 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"encoding/pem"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+func TestMockRequestCertificatePolicyCheck(t *testing.T) {
+	template := `{"id":"t1t2t3t4-0000-11eb-0000-000000000000","name":"Template",` +
+		`"subjectCNRegexes":[".*\\.vfidev\\.com"],"sanRegexes":["allowed\\.vfidev\\.com"],` +
+		`"subjectORegexes":[".*"],"subjectOURegexes":[".*"],"subjectLRegexes":[".*"],"subjectSTRegexes":[".*"],` +
+		`"keyTypes":[{"keyType":"RSA","keyLengths":[2048,4096]}]}`
+	var posted int
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + basePath + "applications/name/App":
+			_, _ = w.Write(successGetAppDetails)
+		case "/" + basePath + "applications/App/certificateissuingtemplates/Template":
+			_, _ = w.Write([]byte(template))
+		case "/" + string(urlResourceCertificateRequests):
+			posted++
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"certificateRequests":[{"id":"r1","status":"REQUESTED"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	cases := []struct {
+		cn       string
+		dnsNames []string
+		field    string
+	}{
+		{cn: "allowed.vfidev.com", dnsNames: []string{"allowed.vfidev.com"}},
+		{cn: "allowed.example.com", field: "common name"},
+		{cn: "allowed.vfidev.com", dnsNames: []string{"other.vfidev.com"}, field: "DNS SANs"},
+	}
+	for _, c := range cases {
+		posted = 0
+		req := &certificate.Request{CsrOrigin: certificate.UserProvidedCSR}
+		if err := req.SetCSR(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: newTestCSR(t, c.cn, c.dnsNames...).Raw})); err != nil {
+			t.Fatal(err)
+		}
+		_, err := conn.RequestCertificate(req)
+		if c.field == "" {
+			if err != nil || posted != 1 {
+				t.Fatalf("%s: expected request to be sent, posted %d, err: %v", c.cn, posted, err)
+			}
+			continue
+		}
+		if !errors.Is(err, verror.UserDataError) || !strings.Contains(err.Error(), c.field) {
+			t.Fatalf("expected user data error naming %s, got %v", c.field, err)
+		}
+		if posted != 0 {
+			t.Fatalf("expected request violating the policy not to be sent")
+		}
+	}
+
+	// the key is checked against the allowed key types and sizes
+	req := &certificate.Request{KeyType: certificate.KeyTypeECDSA}
+	req.Subject.CommonName = "allowed.vfidev.com"
+	if err := req.GeneratePrivateKey(); err != nil {
+		t.Fatal(err)
+	}
+	if err := req.GenerateCSR(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.RequestCertificate(req); !errors.Is(err, verror.UserDataError) || !strings.Contains(err.Error(), "Key Type") {
+		t.Fatalf("expected user data error naming the key type, got %v", err)
+	}
+}
+
+func TestOfflineTemplatePolicySANTypes(t *testing.T) {
+	template := certificateTemplate{
+		SANIPAddressRegexes:  []string{`10\.0\.0\..*`},
+		SANRFC822NameRegexes: []string{`.*@vfidev\.com`},
+		SANURIRegexes:        []string{`spiffe://vfidev\.com/.*`},
+	}
+	template.SubjectCNRegexes = []string{".*"}
+	template.SANRegexes = []string{".*"}
+	template.SubjectORegexes = []string{".*"}
+	template.SubjectOURegexes = []string{".*"}
+	template.SubjectLRegexes = []string{".*"}
+	template.SubjectSTRegexes = []string{".*"}
+	policy := template.toPolicy()
+	newCSR := func(ip string, email string, uri string) *certificate.Request {
+		req := certificate.Request{IPAddresses: []net.IP{net.ParseIP(ip)}, EmailAddresses: []string{email}}
+		u, err := url.Parse(uri)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.URIs = []*url.URL{u}
+		req.Subject.CommonName = "mtls.vfidev.com"
+		if err = req.GeneratePrivateKey(); err != nil {
+			t.Fatal(err)
+		}
+		if err = req.GenerateCSR(); err != nil {
+			t.Fatal(err)
+		}
+		return &req
+	}
+
+	if err := policy.ValidateCertificateRequest(newCSR("10.0.0.1", "ops@vfidev.com", "spiffe://vfidev.com/svc")); err != nil {
+		t.Fatalf("expected CSR to match the policy, err: %s", err)
+	}
+	cases := map[string]*certificate.Request{
+		"IP addresses":    newCSR("192.168.0.1", "ops@vfidev.com", "spiffe://vfidev.com/svc"),
+		"email addresses": newCSR("10.0.0.1", "ops@example.com", "spiffe://vfidev.com/svc"),
+		"URIs":            newCSR("10.0.0.1", "ops@vfidev.com", "spiffe://example.com/svc"),
+	}
+	for field, req := range cases {
+		if err := policy.ValidateCertificateRequest(req); err == nil || !strings.Contains(err.Error(), field) {
+			t.Fatalf("expected error naming %s, got %v", field, err)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+// PolicySpecification is the issuing policy of a zone in a stable format for exporting it with GetPolicy
+// and applying it with SetPolicy, e.g. to another tenant. It marshals to JSON and YAML with the names of the tags.
+//
+// Fields which are nil or empty are left unchanged by SetPolicy. The CA account and product option IDs are
+// specific to a tenant, they should be removed before applying the policy to another one.
+type PolicySpecification struct {
+	CertificateAuthority                *string `json:"certificateAuthority,omitempty" yaml:"certificateAuthority,omitempty"`
+	CertificateAuthorityAccountID       *string `json:"certificateAuthorityAccountId,omitempty" yaml:"certificateAuthorityAccountId,omitempty"`
+	CertificateAuthorityProductOptionID *string `json:"certificateAuthorityProductOptionId,omitempty" yaml:"certificateAuthorityProductOptionId,omitempty"`
+	ProductName                         *string `json:"productName,omitempty" yaml:"productName,omitempty"`
+
+	// The regular expressions must match the whole value
+	SubjectCNRegexes []string `json:"subjectCNRegexes,omitempty" yaml:"subjectCNRegexes,omitempty"`
+	SubjectORegexes  []string `json:"subjectORegexes,omitempty" yaml:"subjectORegexes,omitempty"`
+	SubjectOURegexes []string `json:"subjectOURegexes,omitempty" yaml:"subjectOURegexes,omitempty"`
+	SubjectSTRegexes []string `json:"subjectSTRegexes,omitempty" yaml:"subjectSTRegexes,omitempty"`
+	SubjectLRegexes  []string `json:"subjectLRegexes,omitempty" yaml:"subjectLRegexes,omitempty"`
+	// SubjectCValues are two-letter country codes
+	SubjectCValues       []string `json:"subjectCValues,omitempty" yaml:"subjectCValues,omitempty"`
+	SANRegexes           []string `json:"sanRegexes,omitempty" yaml:"sanRegexes,omitempty"`
+	SANIPAddressRegexes  []string `json:"sanIpAddressRegexes,omitempty" yaml:"sanIpAddressRegexes,omitempty"`
+	SANRFC822NameRegexes []string `json:"sanRfc822NameRegexes,omitempty" yaml:"sanRfc822NameRegexes,omitempty"`
+	SANURIRegexes        []string `json:"sanUriRegexes,omitempty" yaml:"sanUriRegexes,omitempty"`
+
+	KeyTypes                   []PolicyKeyType `json:"keyTypes,omitempty" yaml:"keyTypes,omitempty"`
+	KeyReuse                   *bool           `json:"keyReuse,omitempty" yaml:"keyReuse,omitempty"`
+	ServiceGeneratedKeyAllowed *bool           `json:"serviceGeneratedKeyAllowed,omitempty" yaml:"serviceGeneratedKeyAllowed,omitempty"`
+	// ValidityDays is the maximum validity of the issued certificates
+	ValidityDays *int `json:"validityDays,omitempty" yaml:"validityDays,omitempty"`
+}
+
+// PolicyKeyType is a key type allowed by a policy with its sizes or curves
+type PolicyKeyType struct {
+	// KeyType is "RSA" or "EC"
+	KeyType string `json:"keyType" yaml:"keyType"`
+	// KeySizes are the RSA key sizes in bits
+	KeySizes []int `json:"keySizes,omitempty" yaml:"keySizes,omitempty"`
+	// KeyCurves are the EC curves, e.g. "P256"
+	KeyCurves []string `json:"keyCurves,omitempty" yaml:"keyCurves,omitempty"`
+}
+
+// GetPolicy returns the issuing policy of the zone
+func (c *Connector) GetPolicy(zone string) (*PolicySpecification, error) {
+	template, err := c.fetchZoneTemplate(zone)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := templateSpecOf(template)
+	if err != nil {
+		return nil, err
+	}
+	p := &PolicySpecification{
+		CertificateAuthority:                stringOrNil(spec.CertificateAuthority),
+		CertificateAuthorityAccountID:       stringOrNil(spec.CertificateAuthorityAccountId),
+		CertificateAuthorityProductOptionID: stringOrNil(spec.CertificateAuthorityProductOptionId),
+		ProductName:                         stringOrNil(spec.ProductName),
+		SubjectCNRegexes:                    spec.SubjectCNRegexes,
+		SubjectORegexes:                     spec.SubjectORegexes,
+		SubjectOURegexes:                    spec.SubjectOURegexes,
+		SubjectSTRegexes:                    spec.SubjectSTRegexes,
+		SubjectLRegexes:                     spec.SubjectLRegexes,
+		SubjectCValues:                      spec.SubjectCValues,
+		SANRegexes:                          spec.SANRegexes,
+		SANIPAddressRegexes:                 spec.SANIPAddressRegexes,
+		SANRFC822NameRegexes:                spec.SANRFC822NameRegexes,
+		SANURIRegexes:                       spec.SANURIRegexes,
+		KeyReuse:                            &spec.KeyReuse,
+		ServiceGeneratedKeyAllowed:          &spec.KeyGeneratedByVenafiAllowed,
+	}
+	for _, kt := range spec.KeyTypes {
+		pkt := PolicyKeyType{KeyType: "RSA", KeySizes: kt.KeySizes}
+		if kt.KeyType == certificate.KeyTypeECDSA {
+			pkt = PolicyKeyType{KeyType: "EC"}
+			for i := range kt.KeyCurves {
+				pkt.KeyCurves = append(pkt.KeyCurves, kt.KeyCurves[i].String())
+			}
+		}
+		p.KeyTypes = append(p.KeyTypes, pkt)
+	}
+	if spec.ValidityPeriod > 0 {
+		days := int(spec.ValidityPeriod / (24 * time.Hour))
+		p.ValidityDays = &days
+	}
+	return p, nil
+}
+
+// SetPolicy applies the policy to the issuing template of the zone, the fields of the policy which are unset
+// are left unchanged. The policy is validated before the template is updated.
+func (c *Connector) SetPolicy(zone string, policy PolicySpecification) error {
+	template, err := c.fetchZoneTemplate(zone)
+	if err != nil {
+		return err
+	}
+	spec, err := templateSpecOf(template)
+	if err != nil {
+		return err
+	}
+	setString := func(dst *string, src *string) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+	setList := func(dst *[]string, src []string) {
+		if len(src) > 0 {
+			*dst = src
+		}
+	}
+	setString(&spec.CertificateAuthority, policy.CertificateAuthority)
+	setString(&spec.CertificateAuthorityAccountId, policy.CertificateAuthorityAccountID)
+	setString(&spec.CertificateAuthorityProductOptionId, policy.CertificateAuthorityProductOptionID)
+	setString(&spec.ProductName, policy.ProductName)
+	setList(&spec.SubjectCNRegexes, policy.SubjectCNRegexes)
+	setList(&spec.SubjectORegexes, policy.SubjectORegexes)
+	setList(&spec.SubjectOURegexes, policy.SubjectOURegexes)
+	setList(&spec.SubjectSTRegexes, policy.SubjectSTRegexes)
+	setList(&spec.SubjectLRegexes, policy.SubjectLRegexes)
+	setList(&spec.SubjectCValues, policy.SubjectCValues)
+	setList(&spec.SANRegexes, policy.SANRegexes)
+	setList(&spec.SANIPAddressRegexes, policy.SANIPAddressRegexes)
+	setList(&spec.SANRFC822NameRegexes, policy.SANRFC822NameRegexes)
+	setList(&spec.SANURIRegexes, policy.SANURIRegexes)
+	if len(policy.KeyTypes) > 0 {
+		spec.KeyTypes = nil
+		for _, pkt := range policy.KeyTypes {
+			kt := endpoint.AllowedKeyConfiguration{KeySizes: pkt.KeySizes}
+			if err = kt.KeyType.Set(pkt.KeyType); err != nil {
+				return fmt.Errorf("%w: invalid policy keyTypes: unknown key type %q", verror.UserDataError, pkt.KeyType)
+			}
+			for _, name := range pkt.KeyCurves {
+				curve, ok := parseCurve(name)
+				if !ok {
+					return fmt.Errorf("%w: invalid policy keyTypes: unknown curve %q", verror.UserDataError, name)
+				}
+				kt.KeyCurves = append(kt.KeyCurves, curve)
+			}
+			spec.KeyTypes = append(spec.KeyTypes, kt)
+		}
+	}
+	if policy.KeyReuse != nil {
+		spec.KeyReuse = *policy.KeyReuse
+	}
+	if policy.ServiceGeneratedKeyAllowed != nil {
+		spec.KeyGeneratedByVenafiAllowed = *policy.ServiceGeneratedKeyAllowed
+	}
+	if policy.ValidityDays != nil {
+		if *policy.ValidityDays <= 0 {
+			return fmt.Errorf("%w: invalid policy validityDays: %d is not positive", verror.UserDataError, *policy.ValidityDays)
+		}
+		spec.ValidityPeriod = time.Duration(*policy.ValidityDays) * 24 * time.Hour
+	}
+
+	updated, err := spec.toTemplate()
+	if err != nil {
+		return err
+	}
+	if policy.ValidityDays == nil {
+		// keeps validity periods which aren't a number of days, like "P1Y"
+		updated.ValidityPeriod = template.ValidityPeriod
+	}
+	updated.RecommendedSettings = template.RecommendedSettings
+	updated.Priority = template.Priority
+	return c.putTemplate(template.ID, updated)
+}
+
+// fetchZoneTemplate reads the issuing template of the zone, bypassing the cache
+func (c *Connector) fetchZoneTemplate(zone string) (*certificateTemplate, error) {
+	if err := c.requireAuthentication("read the zone policy"); err != nil {
+		return nil, err
+	}
+	z := cloudZone{zone: strings.TrimSpace(zone)}
+	if err := z.validate(); err != nil {
+		return nil, err
+	}
+	return c.fetchTemplate(z)
+}
+
+var validityPeriodRegexp = regexp.MustCompile(`^P(\d+)D$`)
+
+// templateSpecOf converts the template of the API to the spec of CreateTemplate and UpdateTemplate
+func templateSpecOf(t *certificateTemplate) (TemplateSpec, error) {
+	spec := TemplateSpec{
+		Name:                                t.Name,
+		CertificateAuthority:                t.CertificateAuthority,
+		CertificateAuthorityAccountId:       t.CertificateAuthorityAccountId,
+		CertificateAuthorityProductOptionId: t.CertificateAuthorityProductOptionId,
+		ProductName:                         t.Product.ProductName,
+		SubjectCNRegexes:                    t.SubjectCNRegexes,
+		SubjectORegexes:                     t.SubjectORegexes,
+		SubjectOURegexes:                    t.SubjectOURegexes,
+		SubjectSTRegexes:                    t.SubjectSTRegexes,
+		SubjectLRegexes:                     t.SubjectLRegexes,
+		SubjectCValues:                      t.SubjectCValues,
+		SANRegexes:                          t.SANRegexes,
+		SANIPAddressRegexes:                 t.SANIPAddressRegexes,
+		SANRFC822NameRegexes:                t.SANRFC822NameRegexes,
+		SANURIRegexes:                       t.SANURIRegexes,
+		KeyReuse:                            t.KeyReuse,
+		KeyGeneratedByVenafiAllowed:         t.KeyGeneratedByVenafiAllowed,
+	}
+	for _, kt := range t.KeyTypes {
+		allowed := endpoint.AllowedKeyConfiguration{KeySizes: kt.KeyLengths}
+		if err := allowed.KeyType.Set(string(kt.KeyType)); err != nil {
+			return spec, fmt.Errorf("%w: unsupported key type %q in issuing template %s", verror.ServerBadDataResponce, kt.KeyType, t.Name)
+		}
+		for _, name := range kt.KeyCurves {
+			curve, ok := parseCurve(name)
+			if !ok {
+				return spec, fmt.Errorf("%w: unsupported curve %q in issuing template %s", verror.ServerBadDataResponce, name, t.Name)
+			}
+			allowed.KeyCurves = append(allowed.KeyCurves, curve)
+		}
+		spec.KeyTypes = append(spec.KeyTypes, allowed)
+	}
+	if m := validityPeriodRegexp.FindStringSubmatch(t.ValidityPeriod); m != nil {
+		days, _ := strconv.Atoi(m[1])
+		spec.ValidityPeriod = time.Duration(days) * 24 * time.Hour
+	}
+	return spec, nil
+}
+
+func stringOrNil(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// parseCurve returns the supported curve with the name, like "P256" or "P-256".
+// Unlike EllipticCurve.Set it doesn't fall back to the default curve.
+func parseCurve(name string) (certificate.EllipticCurve, bool) {
+	name = strings.ToUpper(strings.Replace(name, "-", "", 1))
+	for _, curve := range certificate.AllSupportedCurves() {
+		if curve.String() == name {
+			return curve, true
+		}
+	}
+	return certificate.EllipticCurveNotSet, false
+}
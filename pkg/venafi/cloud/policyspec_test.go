@@ -0,0 +1,93 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+func TestMockGetAndSetPolicy(t *testing.T) {
+	var put certificateTemplate
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/"+basePath+"applications/App/certificateissuingtemplates/Template":
+			_, _ = w.Write([]byte(`{"id":"t1","name":"Template","certificateAuthority":"BUILTIN",
+				"product":{"certificateAuthority":"BUILTIN","productName":"Default Product"},"priority":2,
+				"subjectCNRegexes":[".*\\.example\\.com"],"sanRegexes":[".*\\.example\\.com"],"subjectCValues":["US"],
+				"keyTypes":[{"keyType":"RSA","keyLengths":[2048]},{"keyType":"EC","keyCurves":["P256","P384"]}],
+				"keyReuse":true,"validityPeriod":"P1Y"}`))
+		case r.Method == "PUT" && r.URL.Path == "/"+string(urlIssuingTemplates)+"/t1":
+			_ = json.NewDecoder(r.Body).Decode(&put)
+			_, _ = w.Write([]byte(`{"id":"t1"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	policy, err := conn.GetPolicy(mockZone)
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	b, err := json.Marshal(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"certificateAuthority":"BUILTIN","productName":"Default Product",` +
+		`"subjectCNRegexes":[".*\\.example\\.com"],"subjectCValues":["US"],"sanRegexes":[".*\\.example\\.com"],` +
+		`"keyTypes":[{"keyType":"RSA","keySizes":[2048]},{"keyType":"EC","keyCurves":["P256","P384"]}],` +
+		`"keyReuse":true,"serviceGeneratedKeyAllowed":false}`
+	if string(b) != expected {
+		t.Fatalf("unexpected policy %s", b)
+	}
+
+	// the exported policy is applied unchanged
+	var imported PolicySpecification
+	if err = json.Unmarshal(b, &imported); err != nil {
+		t.Fatal(err)
+	}
+	if err = conn.SetPolicy(mockZone, imported); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if put.ID != "t1" || put.ValidityPeriod != "P1Y" || put.Priority != 2 || !put.KeyReuse || len(put.KeyTypes) != 2 {
+		t.Fatalf("unexpected template %+v", put)
+	}
+
+	// the unset fields are left unchanged
+	days := 90
+	err = conn.SetPolicy(mockZone, PolicySpecification{SANRegexes: []string{`.*\.example\.org`}, ValidityDays: &days})
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if put.SANRegexes[0] != `.*\.example\.org` || put.SubjectCNRegexes[0] != `.*\.example\.com` ||
+		put.ValidityPeriod != "P90D" || put.Product.ProductName != "Default Product" || !put.KeyReuse {
+		t.Fatalf("unexpected template %+v", put)
+	}
+
+	err = conn.SetPolicy(mockZone, PolicySpecification{KeyTypes: []PolicyKeyType{{KeyType: "EC", KeyCurves: []string{"P999"}}}})
+	if !errors.Is(err, verror.UserDataError) {
+		t.Fatalf("expected a user data error for an unknown curve, got %v", err)
+	}
+	if _, err = conn.GetPolicy("App"); !errors.Is(err, verror.UserDataError) {
+		t.Fatalf("expected a user data error for a malformed zone, got %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// tokenBucket allows rate events per second with bursts of up to one event
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// reserve takes a token and returns how long the caller has to wait for it
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > 1 {
+		b.tokens = 1
+	}
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// zoneLimiters keeps a rate limiter per zone. A nil value is valid and doesn't limit anything.
+type zoneLimiters struct {
+	mu       sync.Mutex
+	limiters map[string]*tokenBucket
+}
+
+func newZoneLimiters() *zoneLimiters {
+	return &zoneLimiters{limiters: make(map[string]*tokenBucket)}
+}
+
+func (zl *zoneLimiters) set(zone string, rps float64, now time.Time) {
+	zl.mu.Lock()
+	defer zl.mu.Unlock()
+	if rps <= 0 {
+		delete(zl.limiters, zone)
+		return
+	}
+	zl.limiters[zone] = &tokenBucket{rate: rps, tokens: 1, last: now}
+}
+
+// wait blocks until a request to the zone is allowed or ctx is done, in which case ctx.Err() is returned
+func (zl *zoneLimiters) wait(ctx context.Context, zone string, now time.Time) error {
+	if zl == nil {
+		return nil
+	}
+	zl.mu.Lock()
+	b := zl.limiters[zone]
+	zl.mu.Unlock()
+	if b == nil {
+		return nil
+	}
+	delay := b.reserve(now)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// SetZoneRateLimit limits certificate requests to the zone to rps requests per second.
+// The limit is shared by all the copies of the connector. Zero or negative rps removes the limit.
+func (c *Connector) SetZoneRateLimit(zone string, rps float64) {
+	if c.limiters == nil {
+		c.limiters = newZoneLimiters()
+	}
+	c.limiters.set(zone, rps, c.now())
+}
+
+// rateLimitHolder keeps the rate limit reported by the last response having the headers.
+// A nil holder is valid and keeps nothing.
+type rateLimitHolder struct {
+	mu        sync.Mutex
+	known     bool
+	remaining int
+	reset     time.Time
+}
+
+// rateLimitHeaders are the names of the remaining requests and reset headers, in order of preference
+var rateLimitHeaders = [][2]string{
+	{"X-RateLimit-Remaining", "X-RateLimit-Reset"},
+	{"RateLimit-Remaining", "RateLimit-Reset"},
+}
+
+// record keeps the rate limit of the response headers. The reset is either a Unix time or a number of seconds
+// from now.
+func (h *rateLimitHolder) record(header http.Header, now time.Time) {
+	if h == nil {
+		return
+	}
+	for _, names := range rateLimitHeaders {
+		remaining, err := strconv.Atoi(header.Get(names[0]))
+		if err != nil {
+			continue
+		}
+		var reset time.Time
+		if seconds, err := strconv.ParseInt(header.Get(names[1]), 10, 64); err == nil && seconds >= 0 {
+			if seconds > 1000000000 {
+				reset = time.Unix(seconds, 0)
+			} else {
+				reset = now.Add(time.Duration(seconds) * time.Second)
+			}
+		}
+		h.mu.Lock()
+		h.known, h.remaining, h.reset = true, remaining, reset
+		h.mu.Unlock()
+		return
+	}
+}
+
+// LastRateLimit returns the number of requests remaining and the time the limit resets, as reported by the
+// last API response having rate limit headers. remaining is -1 until such a response is received and reset
+// is zero when the response has no reset header. It's read only information, the connector doesn't pace
+// the requests by it.
+func (c *Connector) LastRateLimit() (remaining int, reset time.Time) {
+	if c.rateLimit == nil {
+		return -1, time.Time{}
+	}
+	c.rateLimit.mu.Lock()
+	defer c.rateLimit.mu.Unlock()
+	if !c.rateLimit.known {
+		return -1, time.Time{}
+	}
+	return c.rateLimit.remaining, c.rateLimit.reset
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+)
+
+func TestMockZoneRateLimit(t *testing.T) {
+	conn, server := newMockConnector(t, newMockCA(t))
+	defer server.Close()
+	const otherZone = "App\\Other"
+	conn.SetZoneRateLimit(mockZone, 20)
+
+	requestN := func(zone string, n int) time.Duration {
+		conn.SetZone(zone)
+		// CSRs are generated before timing to measure the limiter only
+		var reqs []*certificate.Request
+		for i := 0; i < n; i++ {
+			reqs = append(reqs, &certificate.Request{ParsedCSR: newTestCSR(t, "limited.vfidev.com")})
+		}
+		start := time.Now()
+		for _, req := range reqs {
+			if _, err := conn.RequestCertificate(req); err != nil {
+				t.Fatalf("err is not nil, err: %s", err)
+			}
+		}
+		return time.Since(start)
+	}
+	if elapsed := requestN(mockZone, 4); elapsed < 140*time.Millisecond {
+		t.Fatalf("expected 4 requests at 20 rps to take at least 150ms, took %v", elapsed)
+	}
+	if elapsed := requestN(otherZone, 4); elapsed >= 140*time.Millisecond {
+		t.Fatalf("expected requests to another zone not to be limited, took %v", elapsed)
+	}
+}
+
+func TestMockZoneRateLimitContext(t *testing.T) {
+	conn, server := newMockConnector(t, newMockCA(t))
+	defer server.Close()
+	conn.SetZoneRateLimit(mockZone, 0.5)
+	if _, err := conn.RequestCertificate(&certificate.Request{ParsedCSR: newTestCSR(t, "limited.vfidev.com")}); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+
+	// the next request has to wait two seconds, the caller gives up earlier
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := conn.WithContext(ctx).RequestCertificate(&certificate.Request{ParsedCSR: newTestCSR(t, "limited.vfidev.com")})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the wait for the rate limit to stop with the context, took %v", elapsed)
+	}
+}
+
+func TestMockLastRateLimit(t *testing.T) {
+	reset := time.Now().Add(time.Minute).Unix()
+	var headers map[string]string
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		for k, v := range headers {
+			w.Header().Set(k, v)
+		}
+		_, _ = w.Write(successGetUserAccount)
+	})
+	defer server.Close()
+
+	if remaining, _ := conn.LastRateLimit(); remaining != -1 {
+		t.Fatalf("expected unknown rate limit before any response, got %d", remaining)
+	}
+	headers = map[string]string{"X-RateLimit-Remaining": "42", "X-RateLimit-Reset": strconv.FormatInt(reset, 10)}
+	if err := conn.Authenticate(&endpoint.Authentication{APIKey: "mock-api-key"}); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if remaining, resetTime := conn.LastRateLimit(); remaining != 42 || resetTime.Unix() != reset {
+		t.Fatalf("expected 42 requests remaining until %d, got %d until %v", reset, remaining, resetTime)
+	}
+
+	// a response without the headers keeps the last values, a relative reset is counted from now
+	headers = nil
+	if err := conn.Authenticate(&endpoint.Authentication{APIKey: "mock-api-key"}); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if remaining, _ := conn.LastRateLimit(); remaining != 42 {
+		t.Fatalf("expected the last rate limit to be kept, got %d", remaining)
+	}
+	headers = map[string]string{"RateLimit-Remaining": "0", "RateLimit-Reset": "30"}
+	if err := conn.Authenticate(&endpoint.Authentication{APIKey: "mock-api-key"}); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if remaining, resetTime := conn.LastRateLimit(); remaining != 0 || time.Until(resetTime) <= 20*time.Second || time.Until(resetTime) > 30*time.Second {
+		t.Fatalf("expected no requests remaining for 30s, got %d until %v", remaining, resetTime)
+	}
+}
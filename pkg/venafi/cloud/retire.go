@@ -0,0 +1,163 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+const (
+	urlResourceCertificateRetirement urlResource = basePath + "certificates/retirement"
+
+	certificateStatusRetired = "RETIRED"
+
+	// retireChunkSize is the number of certificates retired by a single request
+	retireChunkSize = 50
+)
+
+// RetireOptions controls RetireMatching
+type RetireOptions struct {
+	// DryRun only returns the certificates which would be retired, nothing is retired
+	DryRun bool
+	// All allows retiring with a filter which has no custom field or validity bounds and so matches
+	// every certificate of the zone
+	All bool
+}
+
+// RetireMatchingError is returned by RetireMatching when some of the certificates couldn't be retired.
+// Certificates are retired in chunks and Errors has an entry for every chunk, nil for the ones that were retired.
+type RetireMatchingError struct {
+	Errors []error
+}
+
+func (e *RetireMatchingError) Error() string {
+	var failed []string
+	for i, err := range e.Errors {
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("chunk %d: %v", i, err))
+		}
+	}
+	return fmt.Sprintf("%d of %d retirement requests failed: %s", len(failed), len(e.Errors), strings.Join(failed, "; "))
+}
+
+// Unwrap returns the error of the first failed chunk, so errors.Is and errors.As can be used on the result
+func (e *RetireMatchingError) Unwrap() error {
+	for _, err := range e.Errors {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type certificateRetirementRequest struct {
+	CertificateIds []string `json:"certificateIds"`
+}
+
+// RetireMatching retires the certificates of the zone matching the filter and returns them.
+// Certificates which are already retired are skipped, so running it again retires nothing.
+// With options.DryRun the certificates are only returned, so the result can be reviewed before
+// retiring for real. A filter matching the whole zone is refused unless options.All is set.
+// When some chunks of certificates fail to be retired, the retired certificates are returned
+// along with a *RetireMatchingError.
+func (c *Connector) RetireMatching(filter endpoint.Filter, options RetireOptions) ([]certificate.CertificateInfo, error) {
+	if c.currentZone().String() == "" {
+		return nil, fmt.Errorf("%w: empty zone", verror.UserDataError)
+	}
+	if !options.DryRun && !options.All && !narrowsZone(filter) {
+		return nil, fmt.Errorf("%w: filter matches every certificate of the zone, set RetireOptions.All to retire them all", verror.UserDataError)
+	}
+	if err := c.requireAuthentication("retire certificates"); err != nil {
+		return nil, err
+	}
+	const batchSize = 50
+	limit := -1
+	if filter.Limit != nil {
+		limit = *filter.Limit
+	}
+	var ids []string
+	var infos []certificate.CertificateInfo
+	for page := 0; limit != 0; page++ {
+		certs, err := c.searchCertsBatch(page, batchSize, filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, cert := range certs {
+			if limit == 0 {
+				break
+			}
+			if cert.CertificateStatus == certificateStatusRetired {
+				continue
+			}
+			ids = append(ids, cert.Id)
+			infos = append(infos, cert.ToCertificateInfo())
+			limit--
+		}
+		if len(certs) < batchSize {
+			break
+		}
+	}
+	if options.DryRun || len(ids) == 0 {
+		return infos, nil
+	}
+
+	var retired []certificate.CertificateInfo
+	var errs []error
+	var failed bool
+	for start := 0; start < len(ids); start += retireChunkSize {
+		end := start + retireChunkSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		err := c.retireCertificates(ids[start:end])
+		if err == nil {
+			retired = append(retired, infos[start:end]...)
+		}
+		failed = failed || err != nil
+		errs = append(errs, err)
+	}
+	if failed {
+		return retired, &RetireMatchingError{Errors: errs}
+	}
+	return retired, nil
+}
+
+// retireCertificates retires the certificates with the IDs in a single request
+func (c *Connector) retireCertificates(ids []string) error {
+	statusCode, _, body, err := c.request("POST", c.getURL(urlResourceCertificateRetirement), certificateRetirementRequest{CertificateIds: ids})
+	if err != nil {
+		return err
+	}
+	switch statusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusAccepted, http.StatusNoContent:
+		return nil
+	default:
+		return fmt.Errorf("failed to retire certificates: %w", mapStatusToError(statusCode, body))
+	}
+}
+
+// narrowsZone reports whether the filter has a predicate, so it may match less than the whole zone
+func narrowsZone(filter endpoint.Filter) bool {
+	return len(filter.CustomFields) > 0 || !filter.IssuedAfter.IsZero() || !filter.IssuedBefore.IsZero() ||
+		!filter.ExpiresAfter.IsZero() || !filter.ExpiresBefore.IsZero()
+}
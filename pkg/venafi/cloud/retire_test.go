@@ -0,0 +1,141 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+func TestMockRetireMatching(t *testing.T) {
+	statuses := map[string]string{"c1": "ACTIVE", "c2": "RETIRED", "c3": "ACTIVE"}
+	var retirements []certificateRetirementRequest
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + basePath + "applications/name/App":
+			_, _ = w.Write(successGetAppDetails)
+		case "/" + string(urlResourceCertificateSearch):
+			var certs []Certificate
+			for _, id := range []string{"c1", "c2", "c3"} {
+				certs = append(certs, Certificate{Id: id, SubjectCN: []string{id + ".vfidev.com"}, CertificateStatus: statuses[id]})
+			}
+			_ = json.NewEncoder(w).Encode(CertificateSearchResponse{Count: len(certs), Certificates: certs})
+		case "/" + string(urlResourceCertificateRetirement):
+			var req certificateRetirementRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			retirements = append(retirements, req)
+			for _, id := range req.CertificateIds {
+				statuses[id] = certificateStatusRetired
+			}
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	candidates, err := conn.RetireMatching(endpoint.Filter{}, RetireOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if fmt.Sprint(certificateIDs(candidates)) != "[c1 c3]" {
+		t.Fatalf("unexpected dry run candidates %v", certificateIDs(candidates))
+	}
+	if len(retirements) != 0 {
+		t.Fatalf("dry run must not retire certificates, got %+v", retirements)
+	}
+
+	// the whole zone is only retired when asked for explicitly
+	if _, err = conn.RetireMatching(endpoint.Filter{}, RetireOptions{}); !errors.Is(err, verror.UserDataError) || len(retirements) != 0 {
+		t.Fatalf("expected user data error for a filter matching the whole zone, got %v with requests %+v", err, retirements)
+	}
+
+	retired, err := conn.RetireMatching(endpoint.Filter{}, RetireOptions{All: true})
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if fmt.Sprint(certificateIDs(retired)) != "[c1 c3]" || len(retirements) != 1 || fmt.Sprint(retirements[0].CertificateIds) != "[c1 c3]" {
+		t.Fatalf("unexpected retirement of %v with requests %+v", certificateIDs(retired), retirements)
+	}
+
+	retired, err = conn.RetireMatching(endpoint.Filter{}, RetireOptions{All: true})
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if len(retired) != 0 || len(retirements) != 1 {
+		t.Fatalf("retiring again must be a no-op, got %v with requests %+v", certificateIDs(retired), retirements)
+	}
+}
+
+func TestMockRetireMatchingChunks(t *testing.T) {
+	var retirements [][]string
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + basePath + "applications/name/App":
+			_, _ = w.Write(successGetAppDetails)
+		case "/" + string(urlResourceCertificateSearch):
+			var req SearchRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			var certs []Certificate
+			for i := req.Paging.PageNumber * req.Paging.PageSize; i < 120 && len(certs) < req.Paging.PageSize; i++ {
+				certs = append(certs, Certificate{Id: fmt.Sprintf("c%d", i), CertificateStatus: "ACTIVE"})
+			}
+			_ = json.NewEncoder(w).Encode(CertificateSearchResponse{Count: 120, Certificates: certs})
+		case "/" + string(urlResourceCertificateRetirement):
+			var req certificateRetirementRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			retirements = append(retirements, req.CertificateIds)
+			if len(retirements) == 2 {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	filter := endpoint.Filter{ExpiresBefore: time.Now().Add(24 * time.Hour)}
+	retired, err := conn.RetireMatching(filter, RetireOptions{})
+	var retireErr *RetireMatchingError
+	if !errors.As(err, &retireErr) || len(retireErr.Errors) != 3 || retireErr.Errors[0] != nil || retireErr.Errors[1] == nil || retireErr.Errors[2] != nil {
+		t.Fatalf("expected the second chunk to fail, got %v", err)
+	}
+	if len(retirements) != 3 || len(retirements[0]) != retireChunkSize || len(retirements[2]) != 20 {
+		t.Fatalf("expected the certificates to be retired in chunks, got %d requests", len(retirements))
+	}
+	if len(retired) != 70 || retired[50].ID != "c100" {
+		t.Fatalf("expected the certificates of the retired chunks, got %d", len(retired))
+	}
+}
+
+func certificateIDs(infos []certificate.CertificateInfo) []string {
+	ids := make([]string, len(infos))
+	for i, info := range infos {
+		ids[i] = info.ID
+	}
+	return ids
+}
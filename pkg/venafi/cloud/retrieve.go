@@ -0,0 +1,138 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+)
+
+// RetrieveResult is the outcome of retrieving the certificate of a single pickup ID
+type RetrieveResult struct {
+	PickupID      string
+	CertificateID string
+	Certificates  *certificate.PEMCollection
+	Err           error
+}
+
+// RetrieveCertificates waits up to timeout for the certificates of the pickup IDs to be issued and retrieves them.
+// Statuses are checked with GetCertificateStatuses, which reads the pending requests one by one, and every
+// certificate is downloaded once, even when several pickup IDs resolve to it. Results follow the order of
+// the unique pickup IDs, the requests still pending after the timeout have an endpoint.ErrCertificatePending error.
+func (c *Connector) RetrieveCertificates(pickupIDs []string, chainOption certificate.ChainOption, timeout time.Duration) ([]RetrieveResult, error) {
+	if err := c.requireAuthentication("retrieve certificates"); err != nil {
+		return nil, err
+	}
+	var results []RetrieveResult
+	index := make(map[string]int, len(pickupIDs))
+	for _, id := range pickupIDs {
+		if _, ok := index[id]; !ok {
+			index[id] = len(results)
+			results = append(results, RetrieveResult{PickupID: id})
+		}
+	}
+	if chainOption == certificate.ChainOptionRootLast {
+		chainOption = c.defaultChainOption
+	}
+
+	deadline := c.now().Add(timeout)
+	pending := make([]string, 0, len(results))
+	for _, r := range results {
+		pending = append(pending, r.PickupID)
+	}
+	statuses := make(map[string]CertificateRequestStatus, len(results))
+	for len(pending) > 0 {
+		current, err := c.GetCertificateStatuses(pending)
+		if err != nil {
+			if !isTransientError(err) || !c.now().Before(deadline) {
+				return nil, err
+			}
+			if err = c.sleep(c.getPollInterval()); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		var waiting []string
+		for _, id := range pending {
+			status := current[id]
+			statuses[id] = status
+			if status.Status != "ISSUED" && status.Status != "FAILED" {
+				waiting = append(waiting, id)
+			}
+		}
+		pending = waiting
+		if len(pending) == 0 || !c.now().Before(deadline) {
+			break
+		}
+		if err := c.sleep(c.getPollInterval()); err != nil {
+			return nil, err
+		}
+	}
+
+	fetched := make(map[string]*RetrieveResult)
+	for i := range results {
+		r := &results[i]
+		status := statuses[r.PickupID]
+		switch {
+		case status.Status == "FAILED":
+			r.Err = fmt.Errorf("failed to retrieve certificate. Status: %v", status.Status)
+			continue
+		case status.Status != "ISSUED" || status.CertificateID == "":
+			r.Err = endpoint.ErrCertificatePending{CertificateID: r.PickupID, Status: status.Status}
+			continue
+		}
+		r.CertificateID = status.CertificateID
+		f, ok := fetched[status.CertificateID]
+		if !ok {
+			f = &RetrieveResult{}
+			f.Certificates, f.Err = c.fetchCertificateContents(status.CertificateID, chainOption)
+			fetched[status.CertificateID] = f
+		}
+		r.Err = f.Err
+		if f.Certificates != nil {
+			pcc := *f.Certificates
+			pcc.Chain = append([]string(nil), f.Certificates.Chain...)
+			r.Certificates = &pcc
+		}
+	}
+	return results, nil
+}
+
+// fetchCertificateContents downloads the PEM certificate and its chain in the order of chainOption
+func (c *Connector) fetchCertificateContents(certificateID string, chainOption certificate.ChainOption) (*certificate.PEMCollection, error) {
+	url := fmt.Sprintf(c.getURL(urlResourceCertificateRetrievePem), certificateID)
+	url += fmt.Sprintf("?chainOrder=%s&format=PEM", ChainOrderFor(chainOption))
+	statusCode, _, body, err := c.request("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to retrieve certificate: %w", mapStatusToError(statusCode, body))
+	}
+	pcc, err := newPEMCollectionFromResponse(body, chainOption)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.completeChain(&certificate.Request{}, pcc, chainOption); err != nil {
+		return nil, err
+	}
+	return pcc, nil
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+)
+
+func TestMockRetrieveCertificatesDedup(t *testing.T) {
+	cert, err := newSelfSignedCert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var contentFetches int
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + string(urlResourceCertificateSearch):
+			_, _ = w.Write([]byte(`{"certificates":[]}`))
+		case "/" + string(urlResourceCertificateRequests) + "/r1", "/" + string(urlResourceCertificateRequests) + "/r2":
+			_, _ = w.Write([]byte(`{"status":"ISSUED","certificateIds":["c1"]}`))
+		case "/" + string(urlResourceCertificateRequests) + "/r3":
+			_, _ = w.Write([]byte(`{"status":"PENDING"}`))
+		case "/" + string(urlResourceCertificates) + "/c1/contents":
+			contentFetches++
+			_, _ = w.Write([]byte(cert))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	results, err := conn.RetrieveCertificates([]string{"r1", "r2", "r1", "r3"}, certificate.ChainOptionIgnore, 0)
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if contentFetches != 1 {
+		t.Fatalf("expected a single contents fetch, got %d", contentFetches)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected results for 3 unique pickup IDs, got %+v", results)
+	}
+	for _, r := range results[:2] {
+		if r.Err != nil || r.CertificateID != "c1" || r.Certificates == nil || strings.TrimSpace(r.Certificates.Certificate) != strings.TrimSpace(cert) {
+			t.Fatalf("unexpected result %+v", r)
+		}
+	}
+	if !errors.As(results[2].Err, &endpoint.ErrCertificatePending{}) {
+		t.Fatalf("expected pending certificate for r3, got %v", results[2].Err)
+	}
+}
+
+func TestMockRetrieveCertificateRetrieved(t *testing.T) {
+	cert, err := newSelfSignedCert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := pem.Decode([]byte(cert))
+	thumbprint := certThumbprint(b.Bytes)
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + string(urlResourceCertificateSearch):
+			_, _ = w.Write([]byte(`{"certificates":[{"id":"c1","certificateRequestId":"r1"}]}`))
+		case "/" + string(urlResourceCertificateRequests) + "/r1":
+			_, _ = w.Write([]byte(`{"status":"ISSUED","certificateIds":["c1"]}`))
+		case "/" + string(urlResourceCertificates) + "/c1/contents":
+			_, _ = w.Write([]byte(cert))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	expected := certificate.RetrievedCertificate{CertID: "c1", PickupID: "r1", Thumbprint: thumbprint}
+	for _, req := range []*certificate.Request{
+		{PickupID: "r1", ChainOption: certificate.ChainOptionIgnore},
+		{Thumbprint: strings.ToLower(thumbprint)},
+	} {
+		if _, err = conn.RetrieveCertificate(req); err != nil {
+			t.Fatalf("err is not nil, err: %s", err)
+		}
+		if req.Retrieved == nil || *req.Retrieved != expected {
+			t.Fatalf("expected %+v to be retrieved, got %+v", expected, req.Retrieved)
+		}
+	}
+}
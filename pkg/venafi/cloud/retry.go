@@ -0,0 +1,111 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+const (
+	defaultMaxRetries     = 2
+	defaultRetryBaseDelay = 500 * time.Millisecond
+	// maxRetryDelay caps the backoff, a longer Retry-After isn't waited for
+	maxRetryDelay = 30 * time.Second
+)
+
+// SetMaxRetries sets how many times a request failing with 429, 502, 503 or 504 status or a network error
+// is retried. Zero disables retries.
+// POST requests are retried only when the server surely didn't process them: on 429 status or when
+// the connection failed before the request was sent.
+func (c *Connector) SetMaxRetries(retries int) {
+	c.maxRetries = retries
+}
+
+// SetRetryBaseDelay sets the delay before the first retry. It doubles with every retry, with jitter.
+func (c *Connector) SetRetryBaseDelay(delay time.Duration) {
+	c.retryBaseDelay = delay
+}
+
+// retryDelay returns how long to wait before the request is retried, false if it must not be retried
+func (c *Connector) retryDelay(method string, attempt int, statusCode int, header http.Header, err error, sent bool) (time.Duration, bool) {
+	if attempt >= c.maxRetries {
+		return 0, false
+	}
+	idempotent := method == "GET"
+	switch {
+	case err != nil:
+		if !errors.Is(err, verror.ServerUnavailableError) || (sent && !idempotent) {
+			return 0, false
+		}
+	case statusCode == http.StatusTooManyRequests:
+		if d, ok := retryAfter(header, time.Now()); ok {
+			return d, d <= maxRetryDelay
+		}
+	case statusCode == http.StatusBadGateway, statusCode == http.StatusServiceUnavailable, statusCode == http.StatusGatewayTimeout:
+		if !idempotent {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+	return backoff(c.retryBaseDelay, attempt), true
+}
+
+// isTransientError reports whether the request failed for a reason which may go away when retried later
+func isTransientError(err error) bool {
+	return errors.Is(err, verror.ServerTemporaryUnavailableError) || errors.Is(err, verror.ServerUnavailableError)
+}
+
+// backoff doubles the base delay with every attempt up to maxRetryDelay and adds jitter,
+// so clients rejected together don't retry together
+func backoff(base time.Duration, attempt int) time.Duration {
+	d := base
+	for i := 0; i < attempt && d < maxRetryDelay; i++ {
+		d *= 2
+	}
+	if d > maxRetryDelay {
+		d = maxRetryDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// retryAfter parses the Retry-After header given in seconds or as an HTTP date
+func retryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	v := header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+func TestMockRequestRetry(t *testing.T) {
+	var gets, posts int
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			gets++
+			switch gets {
+			case 1:
+				w.WriteHeader(http.StatusServiceUnavailable)
+			case 2:
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+			default:
+				w.WriteHeader(http.StatusOK)
+			}
+		default:
+			posts++
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	defer server.Close()
+
+	statusCode, _, _, err := conn.request("GET", conn.getURL(urlResourceUserAccounts), nil)
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if statusCode != http.StatusOK || gets != 3 {
+		t.Fatalf("expected the GET to succeed on the third attempt, got %d after %d attempts", statusCode, gets)
+	}
+
+	statusCode, _, _, err = conn.request("POST", conn.getURL(urlResourceCertificateRequests), struct{}{})
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if statusCode != http.StatusServiceUnavailable || posts != 1 {
+		t.Fatalf("a POST failing with 503 must not be retried, got %d after %d attempts", statusCode, posts)
+	}
+
+	gets = 0
+	conn.SetMaxRetries(0)
+	statusCode, _, _, _ = conn.request("GET", conn.getURL(urlResourceUserAccounts), nil)
+	if statusCode != http.StatusServiceUnavailable || gets != 1 {
+		t.Fatalf("expected no retries, got %d after %d attempts", statusCode, gets)
+	}
+}
+
+func TestMockRequestRetryDeadline(t *testing.T) {
+	var gets int32
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&gets, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	defer server.Close()
+	conn.SetMaxRetries(5)
+	conn.SetRetryBaseDelay(2 * time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, _, _, err := conn.WithContext(ctx).request("GET", conn.getURL(urlResourceUserAccounts), nil)
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Fatalf("expected to give up before the deadline instead of retrying, took %s", elapsed)
+	}
+	if !errors.Is(err, verror.ServerTemporaryUnavailableError) || !strings.Contains(err.Error(), "past the deadline") {
+		t.Fatalf("expected the last error wrapped, got %v", err)
+	}
+	if atomic.LoadInt32(&gets) != 1 {
+		t.Fatalf("expected a single attempt, got %d", gets)
+	}
+}
+
+func TestMockRetrieveCertificateTransientStatusFailure(t *testing.T) {
+	cert, err := newSelfSignedCert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var statusPolls, contentFetches int
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + string(urlResourceCertificateRequests) + "/r1":
+			statusPolls++
+			// more failures than the request retries, the pickup loop has to carry on
+			if statusPolls <= 4 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":"r1","status":"ISSUED","certificateIds":["c1"]}`))
+		case "/" + string(urlResourceCertificates) + "/c1/contents":
+			contentFetches++
+			if contentFetches == 1 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			_, _ = w.Write([]byte(cert))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+	if err = conn.SetRetrievePollInterval(time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	pcc, err := conn.RetrieveCertificate(&certificate.Request{PickupID: "r1", Timeout: time.Minute})
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if pcc.Certificate == "" || statusPolls != 5 || contentFetches != 2 {
+		t.Fatalf("unexpected retrieval after %d status polls and %d content fetches", statusPolls, contentFetches)
+	}
+
+	// without a timeout there is no pickup deadline to keep polling within
+	statusPolls = 0
+	_, err = conn.RetrieveCertificate(&certificate.Request{PickupID: "r1"})
+	if !errors.Is(err, verror.ServerTemporaryUnavailableError) {
+		t.Fatalf("expected temporary unavailable error, got %v", err)
+	}
+}
+
+func TestOfflineRetryAfter(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{"", 0, false},
+		{"120", 2 * time.Minute, true},
+		{now.Add(time.Minute).Format(http.TimeFormat), time.Minute, true},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+		{"soon", 0, false},
+	}
+	for _, c := range cases {
+		header := http.Header{}
+		if c.value != "" {
+			header.Set("Retry-After", c.value)
+		}
+		d, ok := retryAfter(header, now)
+		if d != c.expected || ok != c.ok {
+			t.Errorf("Retry-After %q: expected %s %t, got %s %t", c.value, c.expected, c.ok, d, ok)
+		}
+	}
+
+	for attempt := 0; attempt < 10; attempt++ {
+		d := backoff(time.Second, attempt)
+		if d > maxRetryDelay || d < time.Second/2 {
+			t.Fatalf("backoff %s of attempt %d is out of range", d, attempt)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+const urlResourceCertificateRevocation urlResource = basePath + "certificates/revocation"
+
+// RevocationReason is the name of a revocation reason in Venafi Cloud
+type RevocationReason string
+
+const (
+	RevocationReasonUnspecified          RevocationReason = "UNSPECIFIED"
+	RevocationReasonKeyCompromise        RevocationReason = "KEY_COMPROMISE"
+	RevocationReasonCACompromise         RevocationReason = "CA_COMPROMISE"
+	RevocationReasonAffiliationChanged   RevocationReason = "AFFILIATION_CHANGED"
+	RevocationReasonSuperseded           RevocationReason = "SUPERSEDED"
+	RevocationReasonCessationOfOperation RevocationReason = "CESSATION_OF_OPERATION"
+)
+
+// RevocationReasonsMap maps *certificate.RevocationRequest.Reason to Venafi Cloud reasons
+var RevocationReasonsMap = map[string]RevocationReason{
+	"":                       RevocationReasonUnspecified,
+	"none":                   RevocationReasonUnspecified,
+	"key-compromise":         RevocationReasonKeyCompromise,
+	"ca-compromise":          RevocationReasonCACompromise,
+	"affiliation-changed":    RevocationReasonAffiliationChanged,
+	"superseded":             RevocationReasonSuperseded,
+	"cessation-of-operation": RevocationReasonCessationOfOperation,
+}
+
+// CRLReasonCode returns the reason code of RFC 5280 CRL entries, the same codes TPP uses
+func (r RevocationReason) CRLReasonCode() int {
+	switch r {
+	case RevocationReasonKeyCompromise:
+		return 1
+	case RevocationReasonCACompromise:
+		return 2
+	case RevocationReasonAffiliationChanged:
+		return 3
+	case RevocationReasonSuperseded:
+		return 4
+	case RevocationReasonCessationOfOperation:
+		return 5
+	default:
+		return 0
+	}
+}
+
+type certificateRevocationRequest struct {
+	CertificateIds   []string         `json:"certificateIds"`
+	RevocationReason RevocationReason `json:"revocationReason"`
+	Comments         string           `json:"comments,omitempty"`
+}
+
+// RevokeCertificate attempts to revoke the certificate found by Thumbprint or by CertificateDN,
+// which is the certificate request ID like for RenewCertificate. Disable isn't supported by Venafi Cloud.
+func (c *Connector) RevokeCertificate(revReq *certificate.RevocationRequest) (err error) {
+	reason, ok := RevocationReasonsMap[revReq.Reason]
+	if !ok {
+		return fmt.Errorf("%w: could not parse revocation reason `%s`", verror.UserDataError, revReq.Reason)
+	}
+	if err = c.requireAuthentication("revoke a certificate"); err != nil {
+		return err
+	}
+	certificateID, err := c.revocationCertificateID(revReq)
+	if err != nil {
+		return err
+	}
+
+	r := certificateRevocationRequest{CertificateIds: []string{certificateID}, RevocationReason: reason, Comments: revReq.Comments}
+	statusCode, _, body, err := c.request("POST", c.getURL(urlResourceCertificateRevocation), r)
+	if err != nil {
+		return err
+	}
+	switch statusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusAccepted, http.StatusNoContent:
+		return nil
+	case http.StatusConflict:
+		return fmt.Errorf("%w: %s", verror.CertificateAlreadyRevokedError, certificateID)
+	default:
+		return fmt.Errorf("failed to revoke certificate: %w", mapStatusToError(statusCode, body))
+	}
+}
+
+func (c *Connector) revocationCertificateID(revReq *certificate.RevocationRequest) (string, error) {
+	switch {
+	case revReq.Thumbprint != "":
+		searchResult, err := c.searchCertificatesByFingerprint(revReq.Thumbprint)
+		if err != nil {
+			return "", fmt.Errorf("failed to find certificate to revoke: %w", err)
+		}
+		if len(searchResult.Certificates) != 1 {
+			return "", fmt.Errorf("%w: expected one certificate with fingerprint %s, found %d", verror.UserDataError, revReq.Thumbprint, len(searchResult.Certificates))
+		}
+		return searchResult.Certificates[0].Id, nil
+	case revReq.CertificateDN != "":
+		status, err := c.getCertificateStatus(revReq.CertificateDN)
+		if err != nil {
+			return "", fmt.Errorf("failed to find certificate to revoke: %w", err)
+		}
+		if len(status.CertificateIdsList) == 0 {
+			return "", fmt.Errorf("%w: certificate request %s has no certificate, status is %s", verror.UserDataError, revReq.CertificateDN, status.Status)
+		}
+		return status.CertificateIdsList[0], nil
+	default:
+		return "", fmt.Errorf("%w: CertificateDN or Thumbprint required to revoke a certificate", verror.UserDataError)
+	}
+}
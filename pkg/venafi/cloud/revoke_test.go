@@ -0,0 +1,99 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+func TestMockRevokeCertificate(t *testing.T) {
+	var revoked []certificateRevocationRequest
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + string(urlResourceCertificateSearch):
+			_, _ = w.Write([]byte(`{"count":1,"certificates":[{"id":"by-thumbprint","certificateRequestId":"r1"}]}`))
+		case "/" + string(urlResourceCertificateRequests) + "/r2":
+			_, _ = w.Write([]byte(`{"id":"r2","status":"ISSUED","certificateIds":["by-request"]}`))
+		case "/" + string(urlResourceCertificateRevocation):
+			var req certificateRevocationRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			for _, previous := range revoked {
+				if previous.CertificateIds[0] == req.CertificateIds[0] {
+					w.WriteHeader(http.StatusConflict)
+					return
+				}
+			}
+			revoked = append(revoked, req)
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	err := conn.RevokeCertificate(&certificate.RevocationRequest{Thumbprint: "A1B2", Reason: "key-compromise", Comments: "leaked"})
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if err = conn.RevokeCertificate(&certificate.RevocationRequest{CertificateDN: "r2"}); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	expected := []certificateRevocationRequest{
+		{CertificateIds: []string{"by-thumbprint"}, RevocationReason: RevocationReasonKeyCompromise, Comments: "leaked"},
+		{CertificateIds: []string{"by-request"}, RevocationReason: RevocationReasonUnspecified},
+	}
+	if len(revoked) != len(expected) {
+		t.Fatalf("expected %d revocations, got %+v", len(expected), revoked)
+	}
+	for i := range expected {
+		if revoked[i].CertificateIds[0] != expected[i].CertificateIds[0] || revoked[i].RevocationReason != expected[i].RevocationReason ||
+			revoked[i].Comments != expected[i].Comments {
+			t.Fatalf("unexpected revocation %+v, expected %+v", revoked[i], expected[i])
+		}
+	}
+
+	err = conn.RevokeCertificate(&certificate.RevocationRequest{CertificateDN: "r2"})
+	if !errors.Is(err, verror.CertificateAlreadyRevokedError) {
+		t.Fatalf("expected already revoked error, got %v", err)
+	}
+	if err = conn.RevokeCertificate(&certificate.RevocationRequest{CertificateDN: "r2", Reason: "unknown"}); !errors.Is(err, verror.UserDataError) {
+		t.Fatalf("expected user data error for an unknown reason, got %v", err)
+	}
+}
+
+func TestOfflineRevocationReasonCRLCode(t *testing.T) {
+	expected := map[string]int{
+		"":                       0,
+		"none":                   0,
+		"key-compromise":         1,
+		"ca-compromise":          2,
+		"affiliation-changed":    3,
+		"superseded":             4,
+		"cessation-of-operation": 5,
+	}
+	for reason, code := range expected {
+		if c := RevocationReasonsMap[reason].CRLReasonCode(); c != code {
+			t.Fatalf("reason %q: expected CRL reason code %d, got %d", reason, code, c)
+		}
+	}
+}
@@ -17,10 +17,12 @@
 package cloud
 
 import (
-	"encoding/json"
+	"crypto/x509"
 	"fmt"
 	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -63,7 +65,29 @@ const (
 	AND   Operator = "AND"
 )
 
+// customFieldOperand matches certificates by a custom field value
+func customFieldOperand(f endpoint.CustomFieldFilter) Operand {
+	return Operand{Field("customFields." + f.Name), EQ, f.Value}
+}
+
+// validityWindowOperands matches certificates by the validity bounds of the filter, zero bounds are skipped
+func validityWindowOperands(filter endpoint.Filter) []Operand {
+	var operands []Operand
+	add := func(field Field, operator Operator, t time.Time) {
+		if !t.IsZero() {
+			operands = append(operands, Operand{field, operator, t.UTC().Format(time.RFC3339)})
+		}
+	}
+	add("validityStart", GTE, filter.IssuedAfter)
+	add("validityStart", LTE, filter.IssuedBefore)
+	add("validityEnd", GTE, filter.ExpiresAfter)
+	add("validityEnd", LTE, filter.ExpiresBefore)
+	return operands
+}
+
 type CertificateSearchResponse struct {
+	// Count is the total number of matches. Some servers omit it, so paging must not depend on it:
+	// pages are read until a short one is returned.
 	Count        int           `json:"count"`
 	Certificates []Certificate `json:"certificates"`
 }
@@ -78,6 +102,12 @@ type Certificate struct {
 	Fingerprint                   string              `json:"fingerprint"`
 	ValidityStart                 string              `json:"validityStart"`
 	ValidityEnd                   string              `json:"validityEnd"`
+	IssuerCN                      []string            `json:"issuerCN"`
+	KeyUsage                      []string            `json:"keyUsage"`
+	ExtendedKeyUsage              []string            `json:"extendedKeyUsage"`
+	CertificateOwnerUserId        string              `json:"certificateOwnerUserId"`
+	Contacts                      []string            `json:"contacts"`
+	CertificateStatus             string              `json:"certificateStatus"`
 	/* ... and many more fields ... */
 }
 
@@ -104,30 +134,64 @@ func (c Certificate) ToCertificateInfo() certificate.CertificateInfo {
 		Thumbprint: c.Fingerprint,
 		ValidFrom:  start,
 		ValidTo:    end,
+		Owner:      c.CertificateOwnerUserId,
+		Contacts:   c.Contacts,
+	}
+	for _, u := range c.KeyUsage {
+		ci.KeyUsage |= keyUsages[normalizeUsageName(u)]
+	}
+	for _, u := range c.ExtendedKeyUsage {
+		if eku, ok := extKeyUsages[normalizeUsageName(u)]; ok {
+			ci.ExtKeyUsage = append(ci.ExtKeyUsage, eku)
+		}
 	}
 	return ci
 }
 
+// keyUsages maps normalized server key usage names to x509 constants
+var keyUsages = map[string]x509.KeyUsage{
+	"digitalsignature":  x509.KeyUsageDigitalSignature,
+	"contentcommitment": x509.KeyUsageContentCommitment,
+	"nonrepudiation":    x509.KeyUsageContentCommitment,
+	"keyencipherment":   x509.KeyUsageKeyEncipherment,
+	"dataencipherment":  x509.KeyUsageDataEncipherment,
+	"keyagreement":      x509.KeyUsageKeyAgreement,
+	"keycertsign":       x509.KeyUsageCertSign,
+	"crlsign":           x509.KeyUsageCRLSign,
+	"encipheronly":      x509.KeyUsageEncipherOnly,
+	"decipheronly":      x509.KeyUsageDecipherOnly,
+}
+
+// extKeyUsages maps normalized server extended key usage names to x509 constants
+var extKeyUsages = map[string]x509.ExtKeyUsage{
+	"anyextendedkeyusage": x509.ExtKeyUsageAny,
+	"serverauth":          x509.ExtKeyUsageServerAuth,
+	"clientauth":          x509.ExtKeyUsageClientAuth,
+	"codesigning":         x509.ExtKeyUsageCodeSigning,
+	"emailprotection":     x509.ExtKeyUsageEmailProtection,
+	"ipsecendsystem":      x509.ExtKeyUsageIPSECEndSystem,
+	"ipsectunnel":         x509.ExtKeyUsageIPSECTunnel,
+	"ipsecuser":           x509.ExtKeyUsageIPSECUser,
+	"timestamping":        x509.ExtKeyUsageTimeStamping,
+	"ocspsigning":         x509.ExtKeyUsageOCSPSigning,
+}
+
+// normalizeUsageName makes "serverAuth", "SERVER_AUTH" and "server-auth" the same
+func normalizeUsageName(name string) string {
+	name = strings.ToLower(name)
+	return strings.NewReplacer("_", "", "-", "", " ", "").Replace(name)
+}
+
 func ParseCertificateSearchResponse(httpStatusCode int, body []byte) (searchResult *CertificateSearchResponse, err error) {
 	switch httpStatusCode {
 	case http.StatusOK:
 		var searchResult = &CertificateSearchResponse{}
-		err = json.Unmarshal(body, searchResult)
+		err = unmarshalJSON(body, searchResult)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse search results: %s, body: %s", err, body)
 		}
 		return searchResult, nil
 	default:
-		if body != nil {
-			respErrors, err := parseResponseErrors(body)
-			if err == nil {
-				respError := fmt.Sprintf("Unexpected status code on Venafi Cloud certificate search. Status: %d\n", httpStatusCode)
-				for _, e := range respErrors {
-					respError += fmt.Sprintf("Error Code: %d Error: %s\n", e.Code, e.Message)
-				}
-				return nil, fmt.Errorf(respError)
-			}
-		}
-		return nil, fmt.Errorf("unexpected status code on Venafi Cloud certificate search. Status: %d", httpStatusCode)
+		return nil, fmt.Errorf("failed to search certificates: %w", mapStatusToError(httpStatusCode, body))
 	}
 }
@@ -17,8 +17,19 @@
 package cloud
 
 import (
+	"crypto/x509"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+	"github.com/Venafi/vcert/v4/pkg/verror"
 )
 
 func TestSearchRequest(t *testing.T) {
@@ -145,3 +156,250 @@ func TestParseCertificateSearchResponse(t *testing.T) {
 		t.Fatal("JSON body should trigger error")
 	}
 }
+
+func TestCertificateToCertificateInfoKeyUsage(t *testing.T) {
+	var searchResult CertificateSearchResponse
+	body := []byte(`{"count":1,"certificates":[{"id":"c1","subjectCN":["tls.vfidev.com"],` +
+		`"keyUsage":["digitalSignature","keyEncipherment"],"extendedKeyUsage":["serverAuth","CLIENT_AUTH","unknownUsage"]}]}`)
+	if err := json.Unmarshal(body, &searchResult); err != nil {
+		t.Fatal(err)
+	}
+	info := searchResult.Certificates[0].ToCertificateInfo()
+	if info.KeyUsage != x509.KeyUsageDigitalSignature|x509.KeyUsageKeyEncipherment {
+		t.Fatalf("unexpected key usage %b", info.KeyUsage)
+	}
+	expected := []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	if !reflect.DeepEqual(info.ExtKeyUsage, expected) {
+		t.Fatalf("unexpected extended key usage %v, expected %v", info.ExtKeyUsage, expected)
+	}
+}
+
+func TestCertificateToCertificateInfoOwner(t *testing.T) {
+	var searchResult CertificateSearchResponse
+	body := []byte(`{"count":2,"certificates":[{"id":"c1","subjectCN":["owned.vfidev.com"],` +
+		`"certificateOwnerUserId":"u1","contacts":["pki@vfidev.com","ops@vfidev.com"]},{"id":"c2","subjectCN":["orphan.vfidev.com"]}]}`)
+	if err := json.Unmarshal(body, &searchResult); err != nil {
+		t.Fatal(err)
+	}
+	owned := searchResult.Certificates[0].ToCertificateInfo()
+	if owned.Owner != "u1" || !reflect.DeepEqual(owned.Contacts, []string{"pki@vfidev.com", "ops@vfidev.com"}) {
+		t.Fatalf("unexpected owner %q and contacts %v", owned.Owner, owned.Contacts)
+	}
+	orphan := searchResult.Certificates[1].ToCertificateInfo()
+	if orphan.Owner != "" || len(orphan.Contacts) != 0 {
+		t.Fatalf("expected no owner and contacts, got %q and %v", orphan.Owner, orphan.Contacts)
+	}
+}
+
+func TestMockListCertificatesCustomFieldFilter(t *testing.T) {
+	var search SearchRequest
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + basePath + "applications/name/App":
+			_, _ = w.Write(successGetAppDetails)
+		case "/" + string(urlResourceCertificateSearch):
+			if err := json.NewDecoder(r.Body).Decode(&search); err != nil {
+				t.Error(err)
+			}
+			_, _ = w.Write([]byte(`{"count":1,"certificates":[{"id":"c1","subjectCN":["team.vfidev.com"]}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	infos, err := conn.ListCertificates(endpoint.Filter{CustomFields: []endpoint.CustomFieldFilter{{Name: "team", Value: "payments"}}})
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if len(infos) != 1 {
+		t.Fatalf("expected 1 certificate, got %d", len(infos))
+	}
+	expected := Operand{"customFields.team", EQ, "payments"}
+	for _, o := range search.Expression.Operands {
+		if reflect.DeepEqual(o, expected) {
+			return
+		}
+	}
+	t.Fatalf("custom field operand %v was not sent, operands: %v", expected, search.Expression.Operands)
+}
+
+func TestMockListCertificatesValidityWindow(t *testing.T) {
+	var search SearchRequest
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + basePath + "applications/name/App":
+			_, _ = w.Write(successGetAppDetails)
+		case "/" + string(urlResourceCertificateSearch):
+			search = SearchRequest{}
+			if err := json.NewDecoder(r.Body).Decode(&search); err != nil {
+				t.Error(err)
+			}
+			_, _ = w.Write([]byte(`{"count":0,"certificates":[]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+	validityOperands := func() []Operand {
+		var operands []Operand
+		for _, o := range search.Expression.Operands {
+			if o.Field == "validityStart" || o.Field == "validityEnd" {
+				operands = append(operands, o)
+			}
+		}
+		return operands
+	}
+
+	issuedAfter := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	expiresBefore := time.Date(2021, 6, 30, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+	filter := endpoint.Filter{WithExpired: true, IssuedAfter: issuedAfter, ExpiresBefore: expiresBefore}
+	if _, err := conn.ListCertificates(filter); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	expected := []Operand{
+		{"validityStart", GTE, "2021-01-01T00:00:00Z"},
+		{"validityEnd", LTE, "2021-06-30T11:00:00Z"},
+	}
+	if !reflect.DeepEqual(validityOperands(), expected) {
+		t.Fatalf("expected operands %v, got %v", expected, validityOperands())
+	}
+
+	// without WithExpired the expiry threshold is kept along with the bounds
+	filter.WithExpired = false
+	if _, err := conn.ListCertificates(filter); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if operands := validityOperands(); len(operands) != 3 || operands[0].Field != "validityEnd" || operands[0].Operator != GTE {
+		t.Fatalf("expected the expiry threshold and the bounds, got %v", operands)
+	}
+
+	if _, err := conn.ListCertificates(endpoint.Filter{WithExpired: true}); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if operands := validityOperands(); len(operands) != 0 {
+		t.Fatalf("unset bounds must not be sent, got %v", operands)
+	}
+}
+
+func TestMockListCertificatesWithoutCount(t *testing.T) {
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + basePath + "applications/name/App":
+			_, _ = w.Write(successGetAppDetails)
+		case "/" + string(urlResourceCertificateSearch):
+			var search SearchRequest
+			if err := json.NewDecoder(r.Body).Decode(&search); err != nil {
+				t.Error(err)
+			}
+			n := 3
+			if search.Paging.PageNumber == 0 {
+				n = search.Paging.PageSize
+			}
+			certs := make([]string, n)
+			for i := range certs {
+				certs[i] = fmt.Sprintf(`{"id":"p%d-%d"}`, search.Paging.PageNumber, i)
+			}
+			_, _ = fmt.Fprintf(w, `{"certificates":[%s]}`, strings.Join(certs, ","))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	infos, err := conn.ListCertificates(endpoint.Filter{})
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if len(infos) != 53 {
+		t.Fatalf("expected 53 certificates, got %d", len(infos))
+	}
+}
+
+func TestMockListCertificatesFunc(t *testing.T) {
+	var pages []int
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + basePath + "applications/name/App":
+			_, _ = w.Write(successGetAppDetails)
+		case "/" + string(urlResourceCertificateSearch):
+			var search SearchRequest
+			if err := json.NewDecoder(r.Body).Decode(&search); err != nil {
+				t.Error(err)
+			}
+			pages = append(pages, search.Paging.PageNumber)
+			certs := make([]string, search.Paging.PageSize)
+			for i := range certs {
+				certs[i] = fmt.Sprintf(`{"id":"p%d-%d"}`, search.Paging.PageNumber, i)
+			}
+			_, _ = fmt.Fprintf(w, `{"certificates":[%s]}`, strings.Join(certs, ","))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	stop := errors.New("stop")
+	var batches [][]certificate.CertificateInfo
+	err := conn.ListCertificatesFunc(endpoint.Filter{}, func(batch []certificate.CertificateInfo) error {
+		batches = append(batches, batch)
+		if len(batches) == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Fatalf("expected the callback error, got %v", err)
+	}
+	if len(batches) != 2 || len(batches[0]) != 50 || batches[1][0].ID != "p1-0" || !reflect.DeepEqual(pages, []int{0, 1}) {
+		t.Fatalf("expected listing to stop after two pages, got %d batches of pages %v", len(batches), pages)
+	}
+
+	limit := 60
+	var ids []string
+	err = conn.ListCertificatesFunc(endpoint.Filter{Limit: &limit}, func(batch []certificate.CertificateInfo) error {
+		for _, info := range batch {
+			ids = append(ids, info.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if len(ids) != limit || ids[limit-1] != "p1-9" {
+		t.Fatalf("expected %d certificates, got %d", limit, len(ids))
+	}
+}
+
+func TestMockSearchCertificates(t *testing.T) {
+	var search SearchRequest
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+string(urlResourceCertificateSearch) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&search); err != nil {
+			t.Error(err)
+		}
+		_, _ = w.Write([]byte(`{"count":1,"certificates":[{"id":"c1","subjectCN":["issuer.vfidev.com"],"issuerCN":["Mock CA"]}]}`))
+	})
+	defer server.Close()
+
+	req := &SearchRequest{
+		Expression: &Expression{Operands: []Operand{{Field: "issuerCN", Operator: EQ, Value: "Mock CA"}}},
+		Paging:     &Paging{PageSize: 10},
+	}
+	resp, err := conn.SearchCertificates(req)
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if resp.Count != 1 || resp.Certificates[0].Id != "c1" {
+		t.Fatalf("unexpected search response %+v", resp)
+	}
+	if search.Expression == nil || search.Expression.Operands[0].Field != "issuerCN" || search.Expression.Operands[0].Value != "Mock CA" {
+		t.Fatalf("unexpected search request %+v", search)
+	}
+	if _, err = conn.SearchCertificates(nil); !errors.Is(err, verror.UserDataError) {
+		t.Fatalf("expected user data error for a nil request, got %v", err)
+	}
+}
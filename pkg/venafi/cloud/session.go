@@ -0,0 +1,114 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+const sessionVersion = 1
+
+// SecretStore keeps the secrets of saved sessions, e.g. in the OS keychain, so they aren't written in plaintext
+// with the session. Secrets are stored and loaded by a reference saved in the session instead.
+type SecretStore interface {
+	Store(ref string, secret []byte) error
+	Load(ref string) ([]byte, error)
+}
+
+// SetSecretStore sets the store of the API key for SaveSession and LoadSession
+func (c *Connector) SetSecretStore(store SecretStore) {
+	c.secretStore = store
+}
+
+type savedSession struct {
+	Version   int    `json:"version"`
+	BaseURL   string `json:"baseUrl"`
+	Zone      string `json:"zone,omitempty"`
+	CompanyID string `json:"companyId"`
+	UserID    string `json:"userId,omitempty"`
+	// APIKeyRef is the reference of the API key in the secret store, empty when a CredentialProvider is used
+	APIKeyRef string `json:"apiKeyRef,omitempty"`
+}
+
+// SaveSession writes the state of the authenticated connector, so another process can resume it with LoadSession
+// without calling Authenticate. The API key is put in the store set with SetSecretStore and only its reference is written.
+// Tokens of a CredentialProvider aren't saved, the provider has to be set again on the resumed connector.
+func (c *Connector) SaveSession(w io.Writer) error {
+	if err := c.requireAuthentication("save the session"); err != nil {
+		return err
+	}
+	state := c.snapshot()
+	s := savedSession{Version: sessionVersion, BaseURL: c.getBaseURL(), Zone: state.zone.String(), CompanyID: state.companyID}
+	if state.user.User != nil {
+		s.UserID = state.user.User.ID
+	}
+	if state.apiKey != "" && c.credentials == nil {
+		if c.secretStore == nil {
+			return fmt.Errorf("%w: a secret store must be set to save the API key", verror.UserDataError)
+		}
+		s.APIKeyRef = "vcert/cloud/" + s.CompanyID + "/" + s.UserID
+		if err := c.secretStore.Store(s.APIKeyRef, []byte(state.apiKey)); err != nil {
+			return fmt.Errorf("%w: failed to store the API key: %v", verror.VcertError, err)
+		}
+	}
+	return json.NewEncoder(w).Encode(s)
+}
+
+// LoadSession resumes a session written by SaveSession, restoring the base URL, the zone and the authentication.
+// The API key is loaded from the store set with SetSecretStore.
+func (c *Connector) LoadSession(r io.Reader) error {
+	var s savedSession
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return fmt.Errorf("%w: failed to parse the session: %v", verror.UserDataError, err)
+	}
+	if s.Version != sessionVersion {
+		return fmt.Errorf("%w: unsupported session version %d", verror.UserDataError, s.Version)
+	}
+	if s.BaseURL == "" || s.CompanyID == "" {
+		return fmt.Errorf("%w: the session doesn't contain the base URL and the company", verror.UserDataError)
+	}
+	baseURL, err := normalizeURL(s.BaseURL)
+	if err != nil {
+		return fmt.Errorf("invalid session: %w", err)
+	}
+	var apiKey string
+	if s.APIKeyRef != "" {
+		if c.secretStore == nil {
+			return fmt.Errorf("%w: a secret store must be set to load the API key", verror.UserDataError)
+		}
+		secret, err := c.secretStore.Load(s.APIKeyRef)
+		if err != nil {
+			return fmt.Errorf("%w: failed to load the API key: %v", verror.AuthError, err)
+		}
+		apiKey = string(secret)
+	}
+	if s.Zone != "" {
+		c.SetZone(s.Zone)
+	}
+	c.state.lock()
+	c.baseURL = baseURL
+	c.apiKey = apiKey
+	c.user = &userDetails{User: &user{ID: s.UserID, CompanyID: s.CompanyID}, Company: &company{ID: s.CompanyID}}
+	c.companyID = s.CompanyID
+	c.state.unlock()
+	c.cache.invalidate()
+	return nil
+}
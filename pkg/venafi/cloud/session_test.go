@@ -0,0 +1,123 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+type memorySecretStore map[string][]byte
+
+func (s memorySecretStore) Store(ref string, secret []byte) error {
+	s[ref] = secret
+	return nil
+}
+
+func (s memorySecretStore) Load(ref string) ([]byte, error) {
+	secret, ok := s[ref]
+	if !ok {
+		return nil, fmt.Errorf("no secret %s", ref)
+	}
+	return secret, nil
+}
+
+func TestMockSession(t *testing.T) {
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(headerNameAPIKey) != "session-api-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/" + string(urlResourceUserAccounts):
+			_, _ = w.Write(successGetUserAccount)
+		case "/" + basePath + "applications/name/App":
+			_, _ = w.Write(successGetAppDetails)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+	if err := conn.Authenticate(&endpoint.Authentication{APIKey: "session-api-key"}); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+
+	var session bytes.Buffer
+	if err := conn.SaveSession(&session); !errors.Is(err, verror.UserDataError) {
+		t.Fatalf("expected user data error without a secret store, got %v", err)
+	}
+	store := memorySecretStore{}
+	conn.SetSecretStore(store)
+	if err := conn.SaveSession(&session); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if strings.Contains(session.String(), "session-api-key") || len(store) != 1 {
+		t.Fatalf("the API key must be kept in the secret store only, session: %s", session.String())
+	}
+
+	resumed, err := NewConnector("https://api.venafi.cloud", "", false, nil)
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	resumed.SetHTTPClient(server.Client())
+	resumed.SetSecretStore(store)
+	if err = resumed.LoadSession(&session); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if resumed.baseURL != conn.baseURL || resumed.zone.String() != mockZone {
+		t.Fatalf("unexpected resumed base URL %s and zone %s", resumed.baseURL, resumed.zone)
+	}
+	if companyID, err := resumed.CompanyID(); err != nil || companyID != "a94d5140-efaf-11e5-b223-d96cf8021ce5" {
+		t.Fatalf("unexpected company ID %s, err: %v", companyID, err)
+	}
+	if _, err = resumed.fetchAppDetailsByName("App"); err != nil {
+		t.Fatalf("resumed session can't be used, err: %s", err)
+	}
+
+	if err = resumed.LoadSession(strings.NewReader(`{"version":1,"baseUrl":"https://api.venafi.cloud/","companyId":"c1","apiKeyRef":"missing"}`)); !errors.Is(err, verror.AuthError) {
+		t.Fatalf("expected auth error for a missing secret, got %v", err)
+	}
+	err = resumed.LoadSession(strings.NewReader(`{"version":1,"baseUrl":"https://key@evil.example/?x=1","companyId":"c1"}`))
+	if !errors.Is(err, verror.UserDataError) || resumed.getBaseURL() != conn.baseURL {
+		t.Fatalf("expected user data error for an invalid base URL, got %v and base URL %s", err, resumed.getBaseURL())
+	}
+
+	// the session can be loaded while the connector is in use
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			_ = resumed.getURL(urlResourceUserAccounts)
+		}
+	}()
+	if err = resumed.LoadSession(strings.NewReader(`{"version":1,"baseUrl":"api2.venafi.cloud","companyId":"c1"}`)); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	wg.Wait()
+	if resumed.getBaseURL() != "https://api2.venafi.cloud/" {
+		t.Fatalf("expected the normalized base URL, got %s", resumed.getBaseURL())
+	}
+}
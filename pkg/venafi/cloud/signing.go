@@ -0,0 +1,34 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"net/http"
+)
+
+// RequestSigner signs requests for gateways in front of the API which require a signature of the request,
+// e.g. an HMAC header derived from the body
+type RequestSigner interface {
+	// Sign is called with the complete request, after the authentication headers are set.
+	// body is the request body, empty for GET requests.
+	Sign(req *http.Request, body []byte) error
+}
+
+// SetRequestSigner sets the signer invoked for every request sent by the connector
+func (c *Connector) SetRequestSigner(signer RequestSigner) {
+	c.signer = signer
+}
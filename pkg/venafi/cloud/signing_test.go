@@ -0,0 +1,75 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+)
+
+type hmacSigner struct {
+	key []byte
+}
+
+func (s hmacSigner) Sign(req *http.Request, body []byte) error {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(req.Method + " " + req.URL.Path + "\n"))
+	mac.Write(body)
+	req.Header.Set("X-Gateway-Signature", hex.EncodeToString(mac.Sum(nil)))
+	return nil
+}
+
+func TestMockRequestSigner(t *testing.T) {
+	signer := hmacSigner{key: []byte("gateway-secret")}
+	var signed int
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		expected := http.Header{}
+		_ = signer.Sign(&http.Request{Method: r.Method, URL: r.URL, Header: expected}, body)
+		if r.Header.Get("X-Gateway-Signature") != expected.Get("X-Gateway-Signature") {
+			t.Errorf("invalid signature for %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		signed++
+		switch r.URL.Path {
+		case "/" + basePath + "applications/name/App":
+			_, _ = w.Write(successGetAppDetails)
+		case "/" + string(urlResourceCertificateRequests):
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"certificateRequests":[{"id":"r1"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+	conn.SetRequestSigner(signer)
+
+	if _, err := conn.RequestCertificate(&certificate.Request{ParsedCSR: newTestCSR(t, "signed.vfidev.com")}); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	// the application and issuing template lookups and the request itself
+	if signed != 3 {
+		t.Fatalf("expected the GETs and the POST to be signed, got %d signed requests", signed)
+	}
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"sync"
+)
+
+// stateLock guards the fields of the connector changed by Authenticate, LoadSession, SetZone and SetHTTPClient,
+// so one connector can serve concurrent calls while they are used. The other setters configure the connector
+// and must be called before it's shared. The lock is shared with the connector copies.
+// A nil lock is valid and locks nothing, for connectors not made by NewConnector.
+type stateLock struct {
+	mu sync.RWMutex
+}
+
+func (l *stateLock) lock() {
+	if l != nil {
+		l.mu.Lock()
+	}
+}
+
+func (l *stateLock) unlock() {
+	if l != nil {
+		l.mu.Unlock()
+	}
+}
+
+func (l *stateLock) rlock() {
+	if l != nil {
+		l.mu.RLock()
+	}
+}
+
+func (l *stateLock) runlock() {
+	if l != nil {
+		l.mu.RUnlock()
+	}
+}
+
+// snapshot returns a copy of the connector, see stateLock
+func (c *Connector) snapshot() Connector {
+	c.state.rlock()
+	defer c.state.runlock()
+	return *c
+}
+
+// currentZone returns the zone of the connector, see stateLock
+func (c *Connector) currentZone() cloudZone {
+	c.state.rlock()
+	defer c.state.runlock()
+	return c.zone
+}
+
+// userDetails returns the details of the authenticated user or nil, see stateLock
+func (c *Connector) userDetails() *userDetails {
+	c.state.rlock()
+	defer c.state.runlock()
+	return c.user
+}
+
+// getAPIKey returns the API key set by Authenticate or LoadSession, see stateLock
+func (c *Connector) getAPIKey() string {
+	c.state.rlock()
+	defer c.state.runlock()
+	return c.apiKey
+}
+
+// getBaseURL returns the base URL of the API, which LoadSession may change, see stateLock
+func (c *Connector) getBaseURL() string {
+	c.state.rlock()
+	defer c.state.runlock()
+	return c.baseURL
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+)
+
+// TestMockConcurrentUse is meant to be run with -race
+func TestMockConcurrentUse(t *testing.T) {
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + basePath + "applications/name/App":
+			_, _ = w.Write(successGetAppDetails)
+		case "/" + string(urlResourceCertificateRequests):
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write(successRequestCertificate)
+		case "/" + string(urlResourceUserAccounts):
+			_, _ = w.Write(successGetUserAccount)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+	client := conn.getHTTPClient()
+
+	var reqs []*certificate.Request
+	for i := 0; i < 8; i++ {
+		reqs = append(reqs, &certificate.Request{ParsedCSR: newTestCSR(t, "concurrent.vfidev.com")})
+	}
+	var wg sync.WaitGroup
+	for _, req := range reqs {
+		wg.Add(1)
+		go func(req *certificate.Request) {
+			defer wg.Done()
+			if _, err := conn.RequestCertificate(req); err != nil {
+				t.Errorf("err is not nil, err: %s", err)
+			}
+		}(req)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 4; i++ {
+			conn.SetZone(mockZone)
+			conn.SetHTTPClient(client)
+			if err := conn.Authenticate(&endpoint.Authentication{APIKey: "mock-api-key"}); err != nil {
+				t.Errorf("err is not nil, err: %s", err)
+			}
+			if _, err := conn.GetUserDetails(); err != nil {
+				t.Errorf("err is not nil, err: %s", err)
+			}
+		}
+	}()
+	wg.Wait()
+}
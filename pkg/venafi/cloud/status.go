@@ -0,0 +1,114 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+// statusBatchSize caps the number of request IDs in a single search operand
+const statusBatchSize = 50
+
+// CertificateRequestStatus is the state of a certificate request
+type CertificateRequestStatus struct {
+	// Status is ISSUED, FAILED, REQUESTED or PENDING
+	Status string
+	// CertificateID is set when the certificate is issued
+	CertificateID string
+	// CertificateIDs are all the certificates issued for the request
+	CertificateIDs []string
+	// FailureReason is the error reported by Venafi Cloud when the status is FAILED
+	FailureReason string
+}
+
+// GetCertificateStatus returns the status of the certificate request without waiting for the certificate,
+// so callers can poll at their own pace and pick up the certificate with RetrieveCertificate once it's issued.
+func (c *Connector) GetCertificateStatus(pickupID string) (*CertificateRequestStatus, error) {
+	if err := c.requireAuthentication("read a certificate request status"); err != nil {
+		return nil, err
+	}
+	s, err := c.getCertificateStatus(pickupID)
+	if err != nil {
+		return nil, err
+	}
+	status := newCertificateRequestStatus(s)
+	return &status, nil
+}
+
+func newCertificateRequestStatus(s *certificateStatus) CertificateRequestStatus {
+	status := CertificateRequestStatus{Status: s.Status, CertificateIDs: s.CertificateIdsList}
+	if len(s.CertificateIdsList) > 0 {
+		status.CertificateID = s.CertificateIdsList[0]
+	}
+	if s.Status == "FAILED" {
+		status.FailureReason = s.ErrorInformation.Message
+		if status.FailureReason == "" {
+			status.FailureReason = s.ErrorInformation.Type
+		}
+	}
+	return status
+}
+
+// GetCertificateStatuses returns the statuses of the given certificate requests by pickup ID.
+// The API has no bulk query of request statuses, only issued requests are found in bulk, with a certificate
+// search per statusBatchSize IDs. Each request which is not issued yet is read with its own call, so polling
+// pending requests still costs a call per request.
+func (c *Connector) GetCertificateStatuses(pickupIDs []string) (map[string]CertificateRequestStatus, error) {
+	if err := c.requireAuthentication("read certificate request statuses"); err != nil {
+		return nil, err
+	}
+	statuses := make(map[string]CertificateRequestStatus, len(pickupIDs))
+	for start := 0; start < len(pickupIDs); start += statusBatchSize {
+		end := start + statusBatchSize
+		if end > len(pickupIDs) {
+			end = len(pickupIDs)
+		}
+		if err := c.searchIssuedRequests(pickupIDs[start:end], statuses); err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range pickupIDs {
+		if _, ok := statuses[id]; ok {
+			continue
+		}
+		s, err := c.getCertificateStatus(id)
+		if err != nil {
+			return nil, err
+		}
+		statuses[id] = newCertificateRequestStatus(s)
+	}
+	return statuses, nil
+}
+
+// searchIssuedRequests adds the requests having an issued certificate to statuses
+func (c *Connector) searchIssuedRequests(pickupIDs []string, statuses map[string]CertificateRequestStatus) error {
+	for page := 0; ; page++ {
+		r, err := c.searchCertificates(&SearchRequest{
+			Expression: &Expression{
+				Operands: []Operand{
+					{"certificateRequestId", IN, pickupIDs},
+				},
+			},
+			Paging: &Paging{PageSize: statusBatchSize, PageNumber: page},
+		})
+		if err != nil {
+			return err
+		}
+		for _, cert := range r.Certificates {
+			statuses[cert.CertificateRequestId] = CertificateRequestStatus{Status: "ISSUED", CertificateID: cert.Id, CertificateIDs: []string{cert.Id}}
+		}
+		if len(r.Certificates) < statusBatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,127 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMockGetCertificateStatuses(t *testing.T) {
+	var searches int
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+string(urlResourceCertificateSearch) {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		searches++
+		var req SearchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		var certs []string
+		for _, id := range req.Expression.Operands[0].Value.([]interface{}) {
+			certs = append(certs, fmt.Sprintf(`{"id":"cert-%s","certificateRequestId":"%s"}`, id, id))
+		}
+		_, _ = fmt.Fprintf(w, `{"certificates":[%s]}`, strings.Join(certs, ","))
+	})
+	defer server.Close()
+
+	statuses, err := conn.GetCertificateStatuses([]string{"r1", "r2", "r3"})
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	expected := map[string]CertificateRequestStatus{
+		"r1": {Status: "ISSUED", CertificateID: "cert-r1", CertificateIDs: []string{"cert-r1"}},
+		"r2": {Status: "ISSUED", CertificateID: "cert-r2", CertificateIDs: []string{"cert-r2"}},
+		"r3": {Status: "ISSUED", CertificateID: "cert-r3", CertificateIDs: []string{"cert-r3"}},
+	}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Fatalf("unexpected statuses\nget:    %v\nexpect: %v", statuses, expected)
+	}
+	if searches != 1 {
+		t.Fatalf("expected a single search, got %d", searches)
+	}
+}
+
+func TestMockGetCertificateStatusesPending(t *testing.T) {
+	var searches, reads int
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/"+string(urlResourceCertificateSearch):
+			searches++
+			_, _ = w.Write([]byte(`{"certificates":[{"id":"cert-r1","certificateRequestId":"r1"}]}`))
+		case strings.HasPrefix(r.URL.Path, "/"+string(urlResourceCertificateRequests)+"/"):
+			reads++
+			_, _ = fmt.Fprintf(w, `{"id":"%s","status":"PENDING"}`, strings.TrimPrefix(r.URL.Path, "/"+string(urlResourceCertificateRequests)+"/"))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	statuses, err := conn.GetCertificateStatuses([]string{"r1", "r2", "r3"})
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if statuses["r1"].Status != "ISSUED" || statuses["r2"].Status != "PENDING" || statuses["r3"].Status != "PENDING" {
+		t.Fatalf("unexpected statuses %v", statuses)
+	}
+	// the issued request is found by the search, each pending one is read separately
+	if searches != 1 || reads != 2 {
+		t.Fatalf("expected a search and 2 status reads, got %d searches and %d reads", searches, reads)
+	}
+}
+
+func TestMockGetCertificateStatus(t *testing.T) {
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + string(urlResourceCertificateRequests) + "/issued":
+			_, _ = w.Write([]byte(`{"id":"issued","status":"ISSUED","certificateIds":["c1","c2"]}`))
+		case "/" + string(urlResourceCertificateRequests) + "/failed":
+			_, _ = w.Write([]byte(`{"id":"failed","status":"FAILED","errorInformation":{"type":"CERTIFICATE_REQUEST_ERROR","code":10733,"message":"CA rejected the request"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	status, err := conn.GetCertificateStatus("issued")
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	expected := CertificateRequestStatus{Status: "ISSUED", CertificateID: "c1", CertificateIDs: []string{"c1", "c2"}}
+	if !reflect.DeepEqual(*status, expected) {
+		t.Fatalf("unexpected status\nget:    %v\nexpect: %v", *status, expected)
+	}
+	status, err = conn.GetCertificateStatus("failed")
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if status.Status != "FAILED" || status.FailureReason != "CA rejected the request" {
+		t.Fatalf("unexpected failed status %+v", *status)
+	}
+	if _, err = conn.GetCertificateStatus("missing"); err == nil {
+		t.Fatal("expected an error for an unknown request")
+	}
+}
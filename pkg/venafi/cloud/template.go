@@ -0,0 +1,269 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+const urlIssuingTemplateByID = urlIssuingTemplates + "/%s"
+
+// TemplateSpec is the issuing policy of a template created or updated by CreateTemplate and UpdateTemplate.
+// The regular expressions must match the whole value.
+type TemplateSpec struct {
+	// Name of the template, used as its alias when it's added to an application
+	Name string
+	// CertificateAuthority is the CA type, e.g. "BUILTIN" or "DIGICERT"
+	CertificateAuthority                string
+	CertificateAuthorityAccountId       string
+	CertificateAuthorityProductOptionId string
+	ProductName                         string
+	// Domains are added to SubjectCNRegexes and SANRegexes with their subdomains, wildcard names are allowed
+	// in them if AllowWildcards is set
+	Domains        []string
+	AllowWildcards bool
+	// SubjectCNRegexes and SANRegexes allow any name when both them and Domains are empty
+	SubjectCNRegexes []string
+	SubjectORegexes  []string
+	SubjectOURegexes []string
+	SubjectSTRegexes []string
+	SubjectLRegexes  []string
+	// SubjectCValues are the allowed two-letter country codes
+	SubjectCValues       []string
+	SANRegexes           []string
+	SANIPAddressRegexes  []string
+	SANRFC822NameRegexes []string
+	SANURIRegexes        []string
+	// KeyTypes are the allowed key types with their sizes or curves, at least one is required
+	KeyTypes []endpoint.AllowedKeyConfiguration
+	KeyReuse bool
+	// KeyGeneratedByVenafiAllowed permits requests with service generated CSR
+	KeyGeneratedByVenafiAllowed bool
+	// ValidityPeriod is the maximum validity of the issued certificates in whole days, the CA default if zero
+	ValidityPeriod time.Duration
+}
+
+// CreateTemplate creates an issuing template from spec and returns its ID.
+// The template can be added to applications with CreateApplication.
+func (c *Connector) CreateTemplate(spec TemplateSpec) (id string, err error) {
+	if err = c.requireAuthentication("create an issuing template"); err != nil {
+		return "", err
+	}
+	template, err := spec.toTemplate()
+	if err != nil {
+		return "", err
+	}
+	created, err := c.postTemplate(template)
+	if err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+// UpdateTemplate replaces the issuing policy of the template with the ID by spec.
+// The cached templates are dropped, so the zones using it are read again.
+func (c *Connector) UpdateTemplate(id string, spec TemplateSpec) error {
+	if err := c.requireAuthentication("update an issuing template"); err != nil {
+		return err
+	}
+	if id == "" {
+		return fmt.Errorf("%w: issuing template ID can not be empty", verror.UserDataError)
+	}
+	template, err := spec.toTemplate()
+	if err != nil {
+		return err
+	}
+	return c.putTemplate(id, template)
+}
+
+// putTemplate replaces the issuing template with the ID and drops the cached templates
+func (c *Connector) putTemplate(id string, template certificateTemplate) error {
+	template.ID = id
+	statusCode, _, body, err := c.request("PUT", fmt.Sprintf(c.getURL(urlIssuingTemplateByID), id), template)
+	if err != nil {
+		return err
+	}
+	if statusCode != http.StatusOK {
+		return fmt.Errorf("failed to update issuing template %s: %w", id, mapStatusToError(statusCode, body))
+	}
+	c.cache.invalidate()
+	return nil
+}
+
+// postTemplate creates the issuing template and returns it as created by the server
+func (c *Connector) postTemplate(template certificateTemplate) (*certificateTemplate, error) {
+	statusCode, _, body, err := c.request("POST", c.getURL(urlIssuingTemplates), template)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != http.StatusCreated && statusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to create issuing template: %w", mapStatusToError(statusCode, body))
+	}
+	var templates struct {
+		Templates []certificateTemplate `json:"certificateIssuingTemplates"`
+	}
+	if err = unmarshalJSON(body, &templates); err != nil || len(templates.Templates) == 0 {
+		return nil, fmt.Errorf("%w: failed to parse created issuing template: %s", verror.ServerBadDataResponce, bodySnippet(body))
+	}
+	return &templates.Templates[0], nil
+}
+
+// deleteTemplate deletes the issuing template with the ID
+func (c *Connector) deleteTemplate(id string) error {
+	statusCode, _, body, err := c.request("DELETE", fmt.Sprintf(c.getURL(urlIssuingTemplateByID), id), nil)
+	if err != nil {
+		return err
+	}
+	if statusCode != http.StatusNoContent && statusCode != http.StatusOK {
+		return fmt.Errorf("failed to delete issuing template %s: %w", id, mapStatusToError(statusCode, body))
+	}
+	return nil
+}
+
+var countryCodeRegexp = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// toTemplate validates the spec and converts it to the template of the API.
+// Errors name the invalid field of the spec.
+func (spec TemplateSpec) toTemplate() (t certificateTemplate, err error) {
+	invalid := func(field string, format string, args ...interface{}) error {
+		return fmt.Errorf("%w: invalid template %s: %s", verror.UserDataError, field, fmt.Sprintf(format, args...))
+	}
+	if spec.Name == "" {
+		return t, invalid("Name", "can not be empty")
+	}
+	if spec.CertificateAuthority == "" {
+		return t, invalid("CertificateAuthority", "can not be empty")
+	}
+	if spec.ProductName == "" {
+		return t, invalid("ProductName", "can not be empty")
+	}
+	t.Name = spec.Name
+	t.CertificateAuthority = spec.CertificateAuthority
+	t.CertificateAuthorityAccountId = spec.CertificateAuthorityAccountId
+	t.CertificateAuthorityProductOptionId = spec.CertificateAuthorityProductOptionId
+	t.Product.CertificateAuthority = spec.CertificateAuthority
+	t.Product.ProductName = spec.ProductName
+
+	t.SubjectCNRegexes = append([]string(nil), spec.SubjectCNRegexes...)
+	t.SANRegexes = append([]string(nil), spec.SANRegexes...)
+	for _, domain := range spec.Domains {
+		if strings.HasPrefix(domain, "*.") || !validHost(domain) || net.ParseIP(domain) != nil {
+			return t, invalid("Domains", "%q is not a domain name", domain)
+		}
+		regexes := domainRegexes(domain, spec.AllowWildcards)
+		t.SubjectCNRegexes = append(t.SubjectCNRegexes, regexes...)
+		t.SANRegexes = append(t.SANRegexes, regexes...)
+	}
+	if len(t.SubjectCNRegexes) == 0 && len(t.SANRegexes) == 0 {
+		t.SubjectCNRegexes = []string{".*"}
+		t.SANRegexes = []string{".*"}
+	}
+	t.SubjectORegexes = spec.SubjectORegexes
+	t.SubjectOURegexes = spec.SubjectOURegexes
+	t.SubjectSTRegexes = spec.SubjectSTRegexes
+	t.SubjectLRegexes = spec.SubjectLRegexes
+	t.SANIPAddressRegexes = spec.SANIPAddressRegexes
+	t.SANRFC822NameRegexes = spec.SANRFC822NameRegexes
+	t.SANURIRegexes = spec.SANURIRegexes
+	for _, f := range []struct {
+		name    string
+		regexes []string
+	}{
+		{"SubjectCNRegexes", t.SubjectCNRegexes},
+		{"SubjectORegexes", t.SubjectORegexes},
+		{"SubjectOURegexes", t.SubjectOURegexes},
+		{"SubjectSTRegexes", t.SubjectSTRegexes},
+		{"SubjectLRegexes", t.SubjectLRegexes},
+		{"SANRegexes", t.SANRegexes},
+		{"SANIPAddressRegexes", t.SANIPAddressRegexes},
+		{"SANRFC822NameRegexes", t.SANRFC822NameRegexes},
+		{"SANURIRegexes", t.SANURIRegexes},
+	} {
+		for _, r := range f.regexes {
+			if _, err = regexp.Compile(r); err != nil {
+				return t, invalid(f.name, "%v", err)
+			}
+		}
+	}
+	for _, country := range spec.SubjectCValues {
+		if !countryCodeRegexp.MatchString(country) {
+			return t, invalid("SubjectCValues", "%q is not a two-letter country code", country)
+		}
+	}
+	t.SubjectCValues = spec.SubjectCValues
+
+	if len(spec.KeyTypes) == 0 {
+		return t, invalid("KeyTypes", "at least one key type is required")
+	}
+	for _, kt := range spec.KeyTypes {
+		switch kt.KeyType {
+		case certificate.KeyTypeRSA:
+			if len(kt.KeySizes) == 0 {
+				return t, invalid("KeyTypes", "RSA key sizes can not be empty")
+			}
+			for _, size := range kt.KeySizes {
+				if size < 1024 || size%1024 != 0 {
+					return t, invalid("KeyTypes", "unsupported RSA key size %d", size)
+				}
+			}
+			t.KeyTypes = append(t.KeyTypes, allowedKeyType{KeyType: "RSA", KeyLengths: kt.KeySizes})
+		case certificate.KeyTypeECDSA:
+			if len(kt.KeyCurves) == 0 {
+				return t, invalid("KeyTypes", "EC key curves can not be empty")
+			}
+			allowed := allowedKeyType{KeyType: "EC"}
+			for i := range kt.KeyCurves {
+				curve := kt.KeyCurves[i].String()
+				if curve == "" {
+					return t, invalid("KeyTypes", "unsupported EC curve %d", kt.KeyCurves[i])
+				}
+				allowed.KeyCurves = append(allowed.KeyCurves, curve)
+			}
+			t.KeyTypes = append(t.KeyTypes, allowed)
+		default:
+			return t, invalid("KeyTypes", "unsupported key type %d", kt.KeyType)
+		}
+	}
+	t.KeyReuse = spec.KeyReuse
+	t.KeyGeneratedByVenafiAllowed = spec.KeyGeneratedByVenafiAllowed
+
+	if spec.ValidityPeriod < 0 || spec.ValidityPeriod%(24*time.Hour) != 0 {
+		return t, invalid("ValidityPeriod", "%s is not a whole number of days", spec.ValidityPeriod)
+	}
+	if spec.ValidityPeriod > 0 {
+		t.ValidityPeriod = fmt.Sprintf("P%dD", spec.ValidityPeriod/(24*time.Hour))
+	}
+	return t, nil
+}
+
+// domainRegexes returns the regexes matching the domain and its subdomains
+func domainRegexes(domain string, allowWildcards bool) []string {
+	quoted := regexp.QuoteMeta(strings.ToLower(domain))
+	if allowWildcards {
+		return []string{quoted, `[*a-z0-9]{1}[a-z0-9.-]*\.` + quoted}
+	}
+	return []string{quoted, `[a-z0-9]{1}[a-z0-9.-]*\.` + quoted}
+}
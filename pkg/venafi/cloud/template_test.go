@@ -0,0 +1,124 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+func TestMockCreateAndUpdateTemplate(t *testing.T) {
+	var posted, put map[string]interface{}
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/"+string(urlIssuingTemplates):
+			_ = json.NewDecoder(r.Body).Decode(&posted)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"certificateIssuingTemplates":[{"id":"t1","name":"Web"}]}`))
+		case r.Method == "PUT" && r.URL.Path == "/"+string(urlIssuingTemplates)+"/t1":
+			_ = json.NewDecoder(r.Body).Decode(&put)
+			_, _ = w.Write([]byte(`{"id":"t1","name":"Web"}`))
+		case r.Method == "PUT":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"errors":[{"code":10001,"message":"Invalid field","args":["validityPeriod","P9999D"]}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	spec := TemplateSpec{
+		Name:                 "Web",
+		CertificateAuthority: "BUILTIN",
+		ProductName:          "Default Product",
+		Domains:              []string{"example.com"},
+		SubjectCValues:       []string{"US"},
+		KeyTypes: []endpoint.AllowedKeyConfiguration{
+			{KeyType: certificate.KeyTypeRSA, KeySizes: []int{2048, 4096}},
+			{KeyType: certificate.KeyTypeECDSA, KeyCurves: []certificate.EllipticCurve{certificate.EllipticCurveP256}},
+		},
+		ValidityPeriod: 90 * 24 * time.Hour,
+	}
+	id, err := conn.CreateTemplate(spec)
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if id != "t1" {
+		t.Fatalf("unexpected template ID %s", id)
+	}
+	if fmt.Sprint(posted["subjectCNRegexes"]) != `[example\.com [a-z0-9]{1}[a-z0-9.-]*\.example\.com]` ||
+		fmt.Sprint(posted["keyTypes"]) != "[map[keyLengths:[2048 4096] keyType:RSA] map[keyCurves:[P256] keyType:EC]]" ||
+		posted["validityPeriod"] != "P90D" {
+		t.Fatalf("unexpected template %v", posted)
+	}
+
+	spec.KeyReuse = true
+	if err = conn.UpdateTemplate("t1", spec); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if put["id"] != "t1" || put["keyReuse"] != true {
+		t.Fatalf("unexpected template %v", put)
+	}
+
+	err = conn.UpdateTemplate("t2", spec)
+	var apiErr *CloudAPIError
+	if !errors.As(err, &apiErr) || !strings.Contains(err.Error(), "Invalid field (validityPeriod, P9999D)") {
+		t.Fatalf("expected the invalid field in the error, got %v", err)
+	}
+}
+
+func TestOfflineTemplateSpecValidation(t *testing.T) {
+	valid := TemplateSpec{
+		Name:                 "Web",
+		CertificateAuthority: "BUILTIN",
+		ProductName:          "Default Product",
+		KeyTypes:             []endpoint.AllowedKeyConfiguration{{KeyType: certificate.KeyTypeRSA, KeySizes: []int{2048}}},
+	}
+	if _, err := valid.toTemplate(); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	cases := []struct {
+		field  string
+		modify func(*TemplateSpec)
+	}{
+		{"Name", func(s *TemplateSpec) { s.Name = "" }},
+		{"Domains", func(s *TemplateSpec) { s.Domains = []string{"*.example.com"} }},
+		{"SANRegexes", func(s *TemplateSpec) { s.SANRegexes = []string{"(unclosed"} }},
+		{"SubjectCValues", func(s *TemplateSpec) { s.SubjectCValues = []string{"USA"} }},
+		{"KeyTypes", func(s *TemplateSpec) { s.KeyTypes = nil }},
+		{"KeyTypes", func(s *TemplateSpec) { s.KeyTypes[0].KeySizes = []int{1000} }},
+		{"ValidityPeriod", func(s *TemplateSpec) { s.ValidityPeriod = 36 * time.Hour }},
+	}
+	for _, c := range cases {
+		spec := valid
+		spec.KeyTypes = []endpoint.AllowedKeyConfiguration{{KeyType: certificate.KeyTypeRSA, KeySizes: []int{2048}}}
+		c.modify(&spec)
+		_, err := spec.toTemplate()
+		if !errors.Is(err, verror.UserDataError) || !strings.Contains(err.Error(), "invalid template "+c.field+":") {
+			t.Fatalf("expected an error for field %s, got %v", c.field, err)
+		}
+	}
+}
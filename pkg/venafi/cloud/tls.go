@@ -0,0 +1,77 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+// tlsStateHolder keeps the TLS connection state of the last response. A nil holder is valid and keeps nothing.
+type tlsStateHolder struct {
+	mu    sync.Mutex
+	state *tls.ConnectionState
+}
+
+func (h *tlsStateHolder) record(res *http.Response) {
+	if h == nil || res.TLS == nil {
+		return
+	}
+	state := *res.TLS
+	h.mu.Lock()
+	h.state = &state
+	h.mu.Unlock()
+}
+
+// LastTLSState returns the TLS connection state of the last response received from the API, e.g. to confirm
+// the negotiated version (state.Version == tls.VersionTLS13) and state.CipherSuite. It's nil until a response
+// is received over TLS.
+func (c *Connector) LastTLSState() *tls.ConnectionState {
+	if c.tlsState == nil {
+		return nil
+	}
+	c.tlsState.mu.Lock()
+	defer c.tlsState.mu.Unlock()
+	if c.tlsState.state == nil {
+		return nil
+	}
+	state := *c.tlsState.state
+	return &state
+}
+
+// SetClientCertificate sets the certificate presented to the servers requiring mutual TLS, like mTLS endpoints
+// of the API or egress proxies. It's installed on the transport of the default client and of a client set with
+// SetHTTPClient, along with the trust pool. Custom round trippers are responsible for their own TLS configuration.
+func (c *Connector) SetClientCertificate(cert tls.Certificate) error {
+	if len(cert.Certificate) == 0 {
+		return fmt.Errorf("%w: client certificate can not be empty", verror.UserDataError)
+	}
+	if cert.PrivateKey == nil {
+		return fmt.Errorf("%w: client certificate has no private key", verror.UserDataError)
+	}
+	c.state.lock()
+	defer c.state.unlock()
+	c.clientCert = &cert
+	// the default client is made again with the certificate
+	c.defaultClient = nil
+	c.applyTransportOptions()
+	return nil
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+func TestMockLastTLSState(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	server.TLS = &tls.Config{MinVersion: tls.VersionTLS13}
+	server.StartTLS()
+	defer server.Close()
+	conn, err := NewConnector(server.URL, "", false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetHTTPClient(server.Client())
+
+	if conn.LastTLSState() != nil {
+		t.Fatal("expected no TLS state before the first response")
+	}
+	if err = conn.Ping(); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	state := conn.LastTLSState()
+	if state == nil {
+		t.Fatal("expected TLS state after the response")
+	}
+	if state.Version != tls.VersionTLS13 {
+		t.Fatalf("expected TLS 1.3, got version %x", state.Version)
+	}
+	if !state.HandshakeComplete || state.CipherSuite == 0 {
+		t.Fatalf("unexpected TLS state %+v", state)
+	}
+}
+
+func TestMockSetClientCertificate(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "vcert client"},
+		NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour), ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+	serverCA := x509.NewCertPool()
+	serverCA.AddCert(server.Certificate())
+
+	// the default client presents the certificate and trusts the pool
+	conn, err := NewConnector(server.URL, "", false, serverCA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetMaxRetries(0)
+	if err = conn.Ping(); err == nil {
+		t.Fatal("err nil, expected the server to require a client certificate")
+	}
+	if err = conn.SetClientCertificate(tls.Certificate{}); !errors.Is(err, verror.UserDataError) {
+		t.Fatalf("expected a user data error for an empty certificate, got %v", err)
+	}
+	if err = conn.SetClientCertificate(tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if err = conn.Ping(); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+
+	// the certificate is installed on a client set before or after it
+	for _, certFirst := range []bool{false, true} {
+		conn, err = NewConnector(server.URL, "", false, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.SetMaxRetries(0)
+		if !certFirst {
+			conn.SetHTTPClient(server.Client())
+		}
+		if err = conn.SetClientCertificate(tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}); err != nil {
+			t.Fatalf("err is not nil, err: %s", err)
+		}
+		if certFirst {
+			conn.SetHTTPClient(server.Client())
+		}
+		if err = conn.Ping(); err != nil {
+			t.Fatalf("certificate set first %v: err is not nil, err: %s", certFirst, err)
+		}
+	}
+}
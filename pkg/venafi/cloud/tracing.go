@@ -0,0 +1,62 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const tracerName = "github.com/Venafi/vcert/v4/pkg/venafi/cloud"
+
+// TracerProvider provides the tracer used for the spans of API calls.
+// It mirrors the OpenTelemetry API, so an adapter of an OpenTelemetry tracer provider can be set
+// without the package depending on OpenTelemetry.
+type TracerProvider interface {
+	Tracer(name string) Tracer
+}
+
+// Tracer starts spans and propagates their context to the server
+type Tracer interface {
+	Start(ctx context.Context, spanName string) (context.Context, Span)
+	// Inject adds the trace context of ctx to the headers of an outgoing request
+	Inject(ctx context.Context, header http.Header)
+}
+
+// Span is a traced API call
+type Span interface {
+	SetAttribute(key string, value interface{})
+	End()
+}
+
+// SetTracerProvider makes the connector record a span for every API call
+func (c *Connector) SetTracerProvider(provider TracerProvider) {
+	c.tracer = provider.Tracer(tracerName)
+}
+
+var uuidSegment = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+
+// spanName is the API resource of the URL with IDs replaced, so spans of the same resource are grouped
+func (c *Connector) spanName(method, url string) string {
+	resource := strings.TrimPrefix(url, c.getBaseURL())
+	if i := strings.Index(resource, "?"); i >= 0 {
+		resource = resource[:i]
+	}
+	return method + " " + uuidSegment.ReplaceAllString(resource, "{id}")
+}
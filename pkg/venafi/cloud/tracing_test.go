@@ -0,0 +1,97 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+type traceIDKey struct{}
+
+type memorySpan struct {
+	name       string
+	attributes map[string]interface{}
+	ended      bool
+}
+
+func (s *memorySpan) SetAttribute(key string, value interface{}) {
+	s.attributes[key] = value
+}
+
+func (s *memorySpan) End() {
+	s.ended = true
+}
+
+type memoryTracer struct {
+	spans []*memorySpan
+}
+
+func (t *memoryTracer) Tracer(_ string) Tracer {
+	return t
+}
+
+func (t *memoryTracer) Start(ctx context.Context, spanName string) (context.Context, Span) {
+	span := &memorySpan{name: spanName, attributes: make(map[string]interface{})}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, traceIDKey{}, spanName), span
+}
+
+func (t *memoryTracer) Inject(ctx context.Context, header http.Header) {
+	header.Set("traceparent", ctx.Value(traceIDKey{}).(string))
+}
+
+func TestMockTracerProvider(t *testing.T) {
+	var traceparents []string
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		switch r.URL.Path {
+		case "/" + basePath + "applications/name/App":
+			_, _ = w.Write(successGetAppDetails)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+	tracer := &memoryTracer{}
+	conn.SetTracerProvider(tracer)
+
+	if _, err := conn.fetchAppDetailsByName("App"); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	_, _ = conn.GetApplicationByID("a1b2c3d4-0000-11eb-0000-000000000000")
+
+	expected := []string{"GET " + basePath + "applications/name/App", "GET " + basePath + "applications/{id}"}
+	if len(tracer.spans) != len(expected) {
+		t.Fatalf("expected %d spans, got %d", len(expected), len(tracer.spans))
+	}
+	for i, span := range tracer.spans {
+		if span.name != expected[i] || !span.ended {
+			t.Fatalf("unexpected span %+v, expected %s", span, expected[i])
+		}
+		if span.attributes["http.method"] != "GET" || span.attributes["retry.count"] != 0 {
+			t.Fatalf("unexpected span attributes %v", span.attributes)
+		}
+		if traceparents[i] != expected[i] {
+			t.Fatalf("trace context was not propagated, got %q", traceparents[i])
+		}
+	}
+	if tracer.spans[0].attributes["http.status_code"] != http.StatusOK || tracer.spans[1].attributes["http.status_code"] != http.StatusNotFound {
+		t.Fatalf("unexpected status codes %v, %v", tracer.spans[0].attributes, tracer.spans[1].attributes)
+	}
+}
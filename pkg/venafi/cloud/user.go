@@ -18,7 +18,10 @@ package cloud
 
 import (
 	"encoding/json"
+	"fmt"
 	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/verror"
 )
 
 type user struct {
@@ -48,3 +51,28 @@ func (u *user) encodeToJSON() ([]byte, error) {
 	b, err := json.Marshal(u)
 	return b, err
 }
+
+// UserDetails describes the user and the company the connector is authenticated as
+type UserDetails struct {
+	UserID      string
+	Username    string
+	Email       string
+	CompanyID   string
+	CompanyName string
+}
+
+// GetUserDetails returns the user and the company read by Authenticate, e.g. to confirm the API key
+// belongs to the expected tenant before issuing
+func (c *Connector) GetUserDetails() (*UserDetails, error) {
+	u := c.userDetails()
+	if u == nil || u.Company == nil {
+		return nil, fmt.Errorf("%w: must be authenticated to get the user details", verror.AuthError)
+	}
+	details := &UserDetails{CompanyID: u.Company.ID, CompanyName: u.Company.Name}
+	if u.User != nil {
+		details.UserID = u.User.ID
+		details.Username = u.User.Username
+		details.Email = u.User.EmailAddress
+	}
+	return details, nil
+}
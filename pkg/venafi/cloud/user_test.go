@@ -0,0 +1,54 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+func TestMockGetUserDetails(t *testing.T) {
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+string(urlResourceUserAccounts) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"user":{"username":"jane@vfidev.com","id":"u1","companyId":"c1","emailAddress":"jane@vfidev.com"},
+			"company":{"id":"c1","name":"Venafi Dev"}}`))
+	})
+	defer server.Close()
+
+	conn.user = nil
+	if _, err := conn.GetUserDetails(); !errors.Is(err, verror.AuthError) {
+		t.Fatalf("expected auth error before Authenticate, got %v", err)
+	}
+	if err := conn.Authenticate(&endpoint.Authentication{APIKey: "mock-api-key"}); err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	details, err := conn.GetUserDetails()
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	expected := UserDetails{UserID: "u1", Username: "jane@vfidev.com", Email: "jane@vfidev.com", CompanyID: "c1", CompanyName: "Venafi Dev"}
+	if *details != expected {
+		t.Fatalf("expected %+v, got %+v", expected, *details)
+	}
+}
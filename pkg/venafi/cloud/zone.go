@@ -0,0 +1,68 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"crypto/x509"
+	"fmt"
+	"strings"
+
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+// zoneSeparator separates the application name and the template alias in the string form of a zone
+const zoneSeparator = "\\"
+
+// Zone is a Venafi Cloud zone: an application and the alias of one of its issuing templates
+type Zone struct {
+	Application   string
+	TemplateAlias string
+}
+
+// String returns the "application\template alias" form of the zone, as accepted by SetZone
+func (z Zone) String() string {
+	return z.Application + zoneSeparator + z.TemplateAlias
+}
+
+// ParseZone parses the "application\template alias" form of a zone. Whitespace around the parts is trimmed.
+func ParseZone(zone string) (Zone, error) {
+	if strings.TrimSpace(zone) == "" {
+		return Zone{}, fmt.Errorf("zone not specified")
+	}
+	segments := strings.Split(zone, zoneSeparator)
+	if len(segments) != 2 {
+		return Zone{}, fmt.Errorf("invalid zone format")
+	}
+	z := Zone{Application: strings.TrimSpace(segments[0]), TemplateAlias: strings.TrimSpace(segments[1])}
+	if z.Application == "" || z.TemplateAlias == "" {
+		return Zone{}, fmt.Errorf("invalid zone format: application name and template alias must not be empty")
+	}
+	return z, nil
+}
+
+// NewConnectorForZone works like NewConnector with a typed zone
+func NewConnectorForZone(url string, zone Zone, verbose bool, trust *x509.CertPool) (*Connector, error) {
+	if _, err := ParseZone(zone.String()); err != nil {
+		return nil, fmt.Errorf("%w: %v: %q", verror.UserDataError, err, zone.String())
+	}
+	return NewConnector(url, zone.String(), verbose, trust)
+}
+
+// SetCloudZone works like SetZone with a typed zone
+func (c *Connector) SetCloudZone(zone Zone) {
+	c.SetZone(zone.String())
+}
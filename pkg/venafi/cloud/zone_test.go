@@ -0,0 +1,96 @@
+/*
+ * Copyright 2021 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/verror"
+)
+
+func TestParseZone(t *testing.T) {
+	cases := []struct {
+		zone     string
+		expected Zone
+		valid    bool
+	}{
+		{"App\\Template", Zone{"App", "Template"}, true},
+		{" My App \\ Default ", Zone{"My App", "Default"}, true},
+		{"", Zone{}, false},
+		{"App", Zone{}, false},
+		{"App\\", Zone{}, false},
+		{"A\\B\\C", Zone{}, false},
+	}
+	for _, c := range cases {
+		z, err := ParseZone(c.zone)
+		if c.valid != (err == nil) || z != c.expected {
+			t.Fatalf("ParseZone(%q) = %+v, %v; expected %+v", c.zone, z, err, c.expected)
+		}
+		if !c.valid {
+			continue
+		}
+		roundTrip, err := ParseZone(z.String())
+		if err != nil || roundTrip != z {
+			t.Fatalf("zone %+v didn't round trip: %+v, %v", z, roundTrip, err)
+		}
+	}
+}
+
+func TestOfflineNewConnectorForZone(t *testing.T) {
+	conn, err := NewConnectorForZone("", Zone{Application: "App", TemplateAlias: "Template"}, false, nil)
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+	if conn.zone.String() != mockZone {
+		t.Fatalf("unexpected zone %q", conn.zone.String())
+	}
+	conn.SetCloudZone(Zone{Application: "Other", TemplateAlias: "Alias"})
+	if conn.zone.getApplicationName() != "Other" || conn.zone.getTemplateAlias() != "Alias" {
+		t.Fatalf("unexpected zone %q", conn.zone.String())
+	}
+	if _, err = NewConnectorForZone("", Zone{Application: "App"}, false, nil); !errors.Is(err, verror.UserDataError) {
+		t.Fatalf("expected user data error for an incomplete zone, got %v", err)
+	}
+}
+
+func TestMockSetZoneMalformed(t *testing.T) {
+	var calls int
+	conn, server := newMockConnector(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusNotFound)
+	})
+	defer server.Close()
+
+	for _, zone := range []string{"App", "App\\", "\\Template", "App\\Template\\Other"} {
+		conn.SetZone(zone)
+		_, err := conn.ReadZoneConfiguration()
+		if !errors.Is(err, verror.UserDataError) || !strings.Contains(err.Error(), `expected "application\template alias"`) {
+			t.Fatalf("expected user data error with the expected format for zone %q, got %v", zone, err)
+		}
+		_, err = conn.RequestCertificate(&certificate.Request{ParsedCSR: newTestCSR(t, "zone.vfidev.com")})
+		if !errors.Is(err, verror.UserDataError) {
+			t.Fatalf("expected user data error for zone %q, got %v", zone, err)
+		}
+	}
+	if calls != 0 {
+		t.Fatalf("expected no request for a malformed zone, got %d", calls)
+	}
+}
@@ -453,6 +453,9 @@ func prepareRequest(req *certificate.Request, zone string) (tppReq certificateRe
 			origin = f.Value
 		}
 	}
+	if req.Origin != "" {
+		origin = req.Origin
+	}
 	tppReq.CASpecificAttributes = append(tppReq.CASpecificAttributes, nameValuePair{Name: "Origin", Value: origin})
 	tppReq.Origin = origin
 
@@ -712,6 +715,9 @@ func (c *Connector) RetrieveCertificate(req *certificate.Request) (certificates
 			return nil, endpoint.ErrCertificatePending{CertificateID: req.PickupID, Status: retrieveResponse.Status}
 		}
 		if time.Now().After(startTime.Add(req.Timeout)) {
+			if req.ReturnPendingOnTimeout {
+				return nil, endpoint.ErrCertificatePending{CertificateID: req.PickupID, Status: retrieveResponse.Status}
+			}
 			return nil, endpoint.ErrRetrieveCertificateTimeout{CertificateID: req.PickupID}
 		}
 		time.Sleep(2 * time.Second)
@@ -8,10 +8,13 @@ var (
 	ServerUnavailableError          = fmt.Errorf("%w: server unavailable", ServerError)
 	ServerTemporaryUnavailableError = fmt.Errorf("%w: temporary", ServerUnavailableError)
 	ServerBadDataResponce           = fmt.Errorf("%w: server returns 400 code. your request has problems", ServerError)
+	ServerConflictError             = fmt.Errorf("%w: server returns 409 code. request conflicts with the current state", ServerError)
 	UserDataError                   = fmt.Errorf("%w: your data contains problems", VcertError)
 	PolicyValidationError           = fmt.Errorf("%w: policy doesn't match request", VcertError)
 	CertificateCheckError           = fmt.Errorf("%w: request doesn't match certificate", UserDataError)
 	AuthError                       = fmt.Errorf("%w: auth error", UserDataError)
 	ZoneNotFoundError               = fmt.Errorf("%w: zone not found", UserDataError)
 	ApplicationNotFoundError        = fmt.Errorf("%w: application not found", UserDataError)
+	CertificateRenewalNeededError   = fmt.Errorf("%w: certificate has to be renewed", VcertError)
+	CertificateAlreadyRevokedError  = fmt.Errorf("%w: certificate is already revoked", ServerConflictError)
 )